@@ -0,0 +1,87 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/candles/pipelines"
+	"github.com/candles/pipelines/candles"
+)
+
+var errInvalidBars = errors.New("invalid bars specification")
+
+// barSpec describes single pipeline to add.
+type barSpec struct {
+	// size is the interval pipeline is added with, in minutes or in bars size units.
+	size int
+	opts []pipelines.Option
+}
+
+// parseBars parses comma separated list of bars,
+// e.g. "5,30,tick:1000,range:0.5,renko:1:AAPL=2:MSFT=0.5".
+// Plain numbers are time intervals in minutes,
+// other bars are described as kind:size with optional TICKER=size overrides.
+func parseBars(s string) ([]barSpec, error) {
+	values := strings.Split(s, ",")
+	specs := make([]barSpec, 0, len(values))
+
+	for _, v := range values {
+		parts := strings.Split(strings.TrimSpace(v), ":")
+		if len(parts) == 1 {
+			n, err := strconv.Atoi(parts[0])
+			if err != nil || n <= 0 {
+				return nil, errInvalidBars
+			}
+
+			specs = append(specs, barSpec{size: n})
+
+			continue
+		}
+
+		spec, err := parseBarSpec(parts)
+		if err != nil {
+			return nil, err
+		}
+
+		specs = append(specs, spec)
+	}
+
+	return specs, nil
+}
+
+// parseBarSpec parses kind, size and per ticker sizes of bars.
+func parseBarSpec(parts []string) (barSpec, error) {
+	kind, err := candles.ParseBarKind(parts[0])
+	if err != nil {
+		return barSpec{}, err
+	}
+
+	size, err := strconv.ParseFloat(parts[1], 64)
+	if err != nil || size <= 0 {
+		return barSpec{}, errInvalidBars
+	}
+
+	// fractional sizes are rounded up, so pipeline interval is never zero.
+	spec := barSpec{
+		size: int(math.Ceil(size)),
+		opts: []pipelines.Option{pipelines.WithBars(kind), pipelines.WithBarSize(size)},
+	}
+
+	for _, p := range parts[2:] {
+		i := strings.IndexByte(p, '=')
+		if i <= 0 {
+			return barSpec{}, errInvalidBars
+		}
+
+		tickerSize, err := strconv.ParseFloat(p[i+1:], 64)
+		if err != nil || tickerSize <= 0 {
+			return barSpec{}, errInvalidBars
+		}
+
+		spec.opts = append(spec.opts, pipelines.WithTickerBarSize(p[:i], tickerSize))
+	}
+
+	return spec, nil
+}
@@ -0,0 +1,342 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+)
+
+var (
+	errUnsupportedFormat = errors.New("unsupported format")
+	errNegative          = errors.New("must not be negative")
+	errNotPositive       = errors.New("must be positive")
+	errEmpty             = errors.New("must not be empty")
+	errSourceConflict    = errors.New("mmap can't be used with listen")
+	errInvalidHour       = errors.New("must be hour of day from 0 to 23")
+)
+
+// config describes CLI configuration, loaded from YAML file and overridden by flags.
+type config struct {
+	Source     sourceConfig     `yaml:"source"`
+	Input      inputConfig      `yaml:"input"`
+	Session    sessionConfig    `yaml:"session"`
+	Validation validationConfig `yaml:"validation"`
+	Intervals  listFlag         `yaml:"intervals"`
+	Outputs    outputsConfig    `yaml:"outputs"`
+	Queues     queuesConfig     `yaml:"queues"`
+	Checkpoint checkpointConfig `yaml:"checkpoint"`
+	Server     serverConfig     `yaml:"server"`
+	Logging    loggingConfig    `yaml:"logging"`
+	// ShutdownTimeout is the time to wait for pipelines to complete after input end.
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+}
+
+// sourceConfig describes input of trades.
+type sourceConfig struct {
+	Filepath string `yaml:"filepath"`
+	Mmap     bool   `yaml:"mmap"`
+	// Listen is the network address to receive trades on instead of reading file.
+	Listen string `yaml:"listen"`
+	// Poll is the interval between checks for new trades at the end of file in serve mode.
+	Poll time.Duration `yaml:"poll"`
+}
+
+// inputConfig describes parsing of trades.
+type inputConfig struct {
+	Format  string `yaml:"format"`
+	Parsers int    `yaml:"parsers"`
+	// Quarantine is the path to file of rejected lines, they are only logged if empty.
+	Quarantine string `yaml:"quarantine"`
+}
+
+// sessionConfig describes hours of day trades are aggregated within.
+type sessionConfig struct {
+	StartHour int `yaml:"start_hour"`
+	EndHour   int `yaml:"end_hour"`
+}
+
+// validationConfig describes data quality rules trades are checked with before aggregation,
+// all rules are disabled by default.
+type validationConfig struct {
+	PositivePrice bool `yaml:"positive_price"`
+	PositiveCount bool `yaml:"positive_count"`
+	// MaxPriceJump is the max ratio of price change between trades of a ticker, disabled if zero.
+	MaxPriceJump float64 `yaml:"max_price_jump"`
+	// NotBefore is the RFC 3339 time trades must not be before, disabled if empty.
+	NotBefore string `yaml:"not_before"`
+	// MaxAhead is the max time trades can be ahead of current time, disabled if zero.
+	MaxAhead     time.Duration `yaml:"max_ahead"`
+	NoDuplicates bool          `yaml:"no_duplicates"`
+	// DuplicatesWindow is the time before the latest trade of a ticker duplicates are detected within.
+	DuplicatesWindow time.Duration `yaml:"duplicates_window"`
+}
+
+// outputsConfig describes candles outputs.
+type outputsConfig struct {
+	Dir          string        `yaml:"dir"`
+	Append       bool          `yaml:"append"`
+	JSON         bool          `yaml:"json"`
+	OrderFlow    bool          `yaml:"order_flow"`
+	TradeTimes   bool          `yaml:"trade_times"`
+	HeikinAshi   bool          `yaml:"heikin_ashi"`
+	Indicators   listFlag      `yaml:"indicators"`
+	PartialEvery time.Duration `yaml:"partial_every"`
+}
+
+// queuesConfig describes queues between pipelines stages.
+type queuesConfig struct {
+	InBuffer   int           `yaml:"in_buffer"`
+	OutBuffer  int           `yaml:"out_buffer"`
+	SinkBuffer int           `yaml:"sink_buffer"`
+	Overflow   string        `yaml:"overflow"`
+	SpillDir   string        `yaml:"spill_dir"`
+	StatsEvery time.Duration `yaml:"stats_every"`
+}
+
+// checkpointConfig describes checkpoints of pipelines state.
+type checkpointConfig struct {
+	Path   string        `yaml:"path"`
+	Every  time.Duration `yaml:"every"`
+	Resume bool          `yaml:"resume"`
+}
+
+// serverConfig describes HTTP API of serve mode.
+type serverConfig struct {
+	Addr string `yaml:"addr"`
+}
+
+// loggingConfig describes logs format.
+type loggingConfig struct {
+	Level  string `yaml:"level"`
+	Format string `yaml:"format"`
+}
+
+// defaultConfig returns configuration used if neither file nor flags override it.
+func defaultConfig() config {
+	return config{
+		Source: sourceConfig{
+			Filepath: "trades.csv",
+			Poll:     time.Second,
+		},
+		Input: inputConfig{
+			Format:  "csv",
+			Parsers: runtime.NumCPU(),
+		},
+		Session: sessionConfig{
+			StartHour: 10,
+			EndHour:   3,
+		},
+		Validation: validationConfig{
+			DuplicatesWindow: time.Minute,
+		},
+		Intervals: listFlag{"5", "30", "240"},
+		Queues: queuesConfig{
+			SinkBuffer: 16,
+			Overflow:   "block",
+		},
+		Checkpoint: checkpointConfig{
+			Every: time.Minute,
+		},
+		Server: serverConfig{
+			Addr: ":8080",
+		},
+		Logging: loggingConfig{
+			Level:  "info",
+			Format: "text",
+		},
+		ShutdownTimeout: 5 * time.Second,
+	}
+}
+
+// load overrides configuration with values present in YAML file.
+// Unknown keys are reported as errors.
+func (c *config) load(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	return yaml.UnmarshalStrict(data, c)
+}
+
+// validate checks configuration values,
+// errors are prefixed with the key of invalid value.
+func (c *config) validate() error {
+	checks := []struct {
+		key string
+		err error
+	}{
+		{"source.filepath", c.checkFilepath()},
+		{"source.mmap", c.checkMmap()},
+		{"source.poll", positive(int64(c.Source.Poll))},
+		{"input.format", c.checkFormat()},
+		{"input.parsers", positive(int64(c.Input.Parsers))},
+		{"session.start_hour", hour(c.Session.StartHour)},
+		{"session.end_hour", hour(c.Session.EndHour)},
+		{"validation.max_price_jump", c.checkMaxPriceJump()},
+		{"validation.not_before", c.checkNotBefore()},
+		{"validation.max_ahead", notNegative(int64(c.Validation.MaxAhead))},
+		{"validation.duplicates_window", notNegative(int64(c.Validation.DuplicatesWindow))},
+		{"intervals", c.checkIntervals()},
+		{"outputs.indicators", c.checkIndicators()},
+		{"outputs.partial_every", notNegative(int64(c.Outputs.PartialEvery))},
+		{"queues.in_buffer", notNegative(int64(c.Queues.InBuffer))},
+		{"queues.out_buffer", notNegative(int64(c.Queues.OutBuffer))},
+		{"queues.sink_buffer", positive(int64(c.Queues.SinkBuffer))},
+		{"queues.overflow", c.checkOverflow()},
+		{"queues.stats_every", notNegative(int64(c.Queues.StatsEvery))},
+		{"checkpoint.every", notNegative(int64(c.Checkpoint.Every))},
+		{"logging.level", c.checkLevel()},
+		{"logging.format", c.checkLogFormat()},
+		{"shutdown_timeout", positive(int64(c.ShutdownTimeout))},
+	}
+
+	for _, check := range checks {
+		if check.err != nil {
+			return fmt.Errorf("%s: %w", check.key, check.err)
+		}
+	}
+
+	return nil
+}
+
+func (c *config) checkFilepath() error {
+	if c.Source.Listen == "" && c.Source.Filepath == "" {
+		return errEmpty
+	}
+
+	return nil
+}
+
+func (c *config) checkMmap() error {
+	if c.Source.Mmap && c.Source.Listen != "" {
+		return errSourceConflict
+	}
+
+	return nil
+}
+
+func (c *config) checkFormat() error {
+	if c.Input.Format != "csv" {
+		return errUnsupportedFormat
+	}
+
+	return nil
+}
+
+func (c *config) checkMaxPriceJump() error {
+	if c.Validation.MaxPriceJump < 0 {
+		return errNegative
+	}
+
+	return nil
+}
+
+func (c *config) checkNotBefore() error {
+	_, err := parseNotBefore(c.Validation.NotBefore)
+	return err
+}
+
+func (c *config) checkIntervals() error {
+	if len(c.Intervals) == 0 {
+		return errEmpty
+	}
+
+	_, err := parseBars(c.Intervals.String())
+
+	return err
+}
+
+func (c *config) checkIndicators() error {
+	_, err := parseIndicators(c.Outputs.Indicators.String())
+	return err
+}
+
+func (c *config) checkOverflow() error {
+	_, err := parseOverflow(c.Queues.Overflow)
+	return err
+}
+
+func (c *config) checkLevel() error {
+	_, err := logrus.ParseLevel(c.Logging.Level)
+	return err
+}
+
+func (c *config) checkLogFormat() error {
+	if c.Logging.Format != "text" && c.Logging.Format != "json" {
+		return errUnsupportedFormat
+	}
+
+	return nil
+}
+
+func hour(h int) error {
+	const hoursInDay = 24
+
+	if h < 0 || h >= hoursInDay {
+		return errInvalidHour
+	}
+
+	return nil
+}
+
+func positive(n int64) error {
+	if n <= 0 {
+		return errNotPositive
+	}
+
+	return nil
+}
+
+func notNegative(n int64) error {
+	if n < 0 {
+		return errNegative
+	}
+
+	return nil
+}
+
+// newLogger creates logger configured by logging config.
+// Config has to be validated.
+func (c *config) newLogger() *logrus.Logger {
+	logger := logrus.New()
+
+	if level, err := logrus.ParseLevel(c.Logging.Level); err == nil {
+		logger.SetLevel(level)
+	}
+
+	if c.Logging.Format == "json" {
+		logger.SetFormatter(&logrus.JSONFormatter{})
+	}
+
+	return logger
+}
+
+// listFlag is a list of values set by comma separated flag value.
+type listFlag []string
+
+// String returns comma separated values.
+func (l listFlag) String() string {
+	return strings.Join(l, ",")
+}
+
+// Set replaces values with comma separated values of s.
+func (l *listFlag) Set(s string) error {
+	*l = nil
+
+	if s == "" {
+		return nil
+	}
+
+	for _, v := range strings.Split(s, ",") {
+		*l = append(*l, strings.TrimSpace(v))
+	}
+
+	return nil
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/candles/pipelines/candles"
+)
+
+var (
+	errInvalidKind   = errors.New("invalid kind, expected trades or candles")
+	errInvalidFormat = errors.New("invalid format, expected csv or json")
+)
+
+// record is a trade or candle converted between formats.
+type record interface {
+	String() string
+}
+
+// decoders parse records of each kind from lines of each format.
+var decoders = map[string]map[string]func(line string) (record, error){
+	"trades": {
+		"csv": func(line string) (record, error) {
+			return candles.TradeFromString(line)
+		},
+		"json": func(line string) (record, error) {
+			var tr candles.Trade
+			err := json.Unmarshal([]byte(line), &tr)
+
+			return tr, err
+		},
+	},
+	"candles": {
+		"csv": func(line string) (record, error) {
+			c, err := candles.CandleFromString(line)
+			return &c, err
+		},
+		"json": func(line string) (record, error) {
+			var c candles.Candle
+			err := json.Unmarshal([]byte(line), &c)
+
+			return &c, err
+		},
+	},
+}
+
+// encoders format records as lines of each format.
+var encoders = map[string]func(r record) (string, error){
+	"csv": func(r record) (string, error) {
+		return r.String(), nil
+	},
+	"json": func(r record) (string, error) {
+		data, err := json.Marshal(r)
+		return string(data), err
+	},
+}
+
+// runConvert runs convert subcommand, which converts trades or candles
+// between CSV and JSON lines formats.
+// Candles are written to CSV without additional columns.
+func runConvert(args []string) error {
+	var kind, from, to, in, out string
+
+	fs := flag.NewFlagSet("convert", flag.ExitOnError)
+	fs.StringVar(&kind, "kind", "trades", "kind of converted records: trades or candles")
+	fs.StringVar(&from, "from", "csv", "format of input: csv or json")
+	fs.StringVar(&to, "to", "json", "format of output: csv or json")
+	fs.StringVar(&in, "in", "trades.csv", "path to input file")
+	fs.StringVar(&out, "out", "", "path to output file, standard output if empty")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	formats, ok := decoders[kind]
+	if !ok {
+		return errInvalidKind
+	}
+
+	decode, ok := formats[from]
+	if !ok {
+		return errInvalidFormat
+	}
+
+	encode, ok := encoders[to]
+	if !ok {
+		return errInvalidFormat
+	}
+
+	var dst io.Writer = os.Stdout
+
+	if out != "" {
+		f, err := os.Create(out)
+		if err != nil {
+			return err
+		}
+
+		defer f.Close()
+
+		dst = f
+	}
+
+	w := bufio.NewWriter(dst)
+
+	err := readLines(in, func(n int, line string, err error) error {
+		if err != nil {
+			return fmt.Errorf("line %d: %w", n, err)
+		}
+
+		r, err := decode(line)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", n, err)
+		}
+
+		s, err := encode(r)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", n, err)
+		}
+
+		_, err = w.WriteString(s + "\n")
+
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return w.Flush()
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/candles/pipelines/candles"
+)
+
+var errInvalidIndicators = errors.New("invalid indicators specification")
+
+// parseIndicators parses comma separated list of indicators,
+// e.g. "sma:20,ema:12,rsi,macd:12:26:9,bb:20:2,atr:14,vwap".
+// Indicator kind is followed by optional parameters, defaults are used for missing ones.
+func parseIndicators(s string) ([]candles.IndicatorSpec, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	values := strings.Split(s, ",")
+	specs := make([]candles.IndicatorSpec, 0, len(values))
+
+	for _, v := range values {
+		parts := strings.Split(strings.TrimSpace(v), ":")
+
+		kind, err := candles.ParseIndicatorKind(parts[0])
+		if err != nil {
+			return nil, err
+		}
+
+		spec := candles.IndicatorSpec{Kind: kind}
+
+		for _, p := range parts[1:] {
+			param, err := strconv.ParseFloat(p, 64)
+			if err != nil {
+				return nil, errInvalidIndicators
+			}
+
+			spec.Params = append(spec.Params, param)
+		}
+
+		specs = append(specs, spec)
+	}
+
+	return specs, nil
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/candles/pipelines/candles"
+)
+
+// tickerSummary describes trades of a single ticker.
+type tickerSummary struct {
+	Ticker   string    `json:"ticker"`
+	Trades   int       `json:"trades"`
+	From     time.Time `json:"from"`
+	To       time.Time `json:"to"`
+	MinPrice float64   `json:"min_price"`
+	MaxPrice float64   `json:"max_price"`
+}
+
+// add adds trade to summary.
+func (s *tickerSummary) add(tr candles.Trade) {
+	if s.Trades == 0 {
+		s.From, s.To = tr.Timestamp, tr.Timestamp
+		s.MinPrice, s.MaxPrice = tr.Price(), tr.Price()
+	}
+
+	s.Trades++
+
+	if tr.Timestamp.Before(s.From) {
+		s.From = tr.Timestamp
+	}
+
+	if tr.Timestamp.After(s.To) {
+		s.To = tr.Timestamp
+	}
+
+	if tr.Price() < s.MinPrice {
+		s.MinPrice = tr.Price()
+	}
+
+	if tr.Price() > s.MaxPrice {
+		s.MaxPrice = tr.Price()
+	}
+}
+
+// runInspect runs inspect subcommand, which prints summaries of trades per ticker.
+// Invalid lines are skipped.
+func runInspect(args []string) error {
+	var (
+		path   string
+		asJSON bool
+	)
+
+	fs := flag.NewFlagSet("inspect", flag.ExitOnError)
+	fs.StringVar(&path, "filepath", "trades.csv", "path to file with trades")
+	fs.BoolVar(&asJSON, "json", false, "print summaries as JSON")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var (
+		summaries = make(map[string]*tickerSummary)
+		invalid   int
+	)
+
+	p := candles.NewParser()
+
+	err := readLines(path, func(_ int, line string, err error) error {
+		if err != nil {
+			invalid++
+			return nil
+		}
+
+		tr, err := p.Parse([]byte(line))
+		if err != nil {
+			invalid++
+			return nil
+		}
+
+		s, ok := summaries[tr.Ticker()]
+		if !ok {
+			s = &tickerSummary{Ticker: tr.Ticker()}
+			summaries[tr.Ticker()] = s
+		}
+
+		s.add(tr)
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if invalid > 0 {
+		logrus.Warnf("%d invalid lines are skipped", invalid)
+	}
+
+	out := make([]tickerSummary, 0, len(summaries))
+	for _, s := range summaries {
+		out = append(out, *s)
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Ticker < out[j].Ticker
+	})
+
+	if asJSON {
+		return json.NewEncoder(os.Stdout).Encode(out)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+	if _, err = fmt.Fprintln(w, "TICKER\tTRADES\tFROM\tTO\tMIN PRICE\tMAX PRICE"); err != nil {
+		return err
+	}
+
+	for _, s := range out {
+		_, err = fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t%s\n",
+			s.Ticker, s.Trades,
+			s.From.Format(time.RFC3339Nano), s.To.Format(time.RFC3339Nano),
+			strconv.FormatFloat(s.MinPrice, 'f', -1, 64), strconv.FormatFloat(s.MaxPrice, 'f', -1, 64),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return w.Flush()
+}
@@ -9,29 +9,251 @@ import (
 
 	"github.com/candles/files"
 	"github.com/candles/pipelines"
+	"github.com/candles/pipelines/candles"
+	"github.com/candles/server"
 )
 
-var filepath string
+var (
+	// cfg is the configuration loaded from file and overridden by flags.
+	cfg        = defaultConfig()
+	configPath string
+)
+
+// source describes input of trades for pipelines.
+type source interface {
+	C() chan files.Batch
+	StartChan() chan struct{}
+	Init()
+}
 
-const timeToWait = 5
+// commands are subcommands run instead of building candles.
+var commands = map[string]func(args []string) error{
+	"query":    runQuery,
+	"validate": runValidate,
+	"inspect":  runInspect,
+	"convert":  runConvert,
+}
 
+// main builds candles from trades, which is done by default or by build subcommand,
+// serve subcommand also serves built candles over HTTP.
+// Other subcommands are described by commands.
 func main() {
-	flag.StringVar(&filepath, "filepath", "trades.csv", "path to files with trades")
-	flag.Parse()
+	args := os.Args[1:]
 
-	logger := logrus.New()
+	if len(args) > 0 {
+		if run, ok := commands[args[0]]; ok {
+			if err := run(args[1:]); err != nil {
+				logrus.Errorf("%s failed: %v", args[0], err)
+				os.Exit(1)
+			}
 
-	reader, err := files.NewReader(filepath, logger)
-	if err != nil {
-		logger.Errorf("can't init file reader: %v", err)
+			return
+		}
+	}
+
+	serveMode := len(args) > 0 && args[0] == "serve"
+
+	if len(args) > 0 && args[0] == "build" {
+		args = args[1:]
+	}
+
+	if serveMode {
+		args = args[1:]
+
+		flag.StringVar(&cfg.Server.Addr, "addr", cfg.Server.Addr, "address to serve HTTP API on")
+		flag.DurationVar(&cfg.Source.Poll, "poll", cfg.Source.Poll,
+			"interval between checks for new trades at the end of input")
+	}
+
+	flag.StringVar(&configPath, "config", "", "path to YAML config file, flags override its values")
+	flag.StringVar(&cfg.Source.Filepath, "filepath", cfg.Source.Filepath, "path to files with trades")
+	flag.StringVar(&cfg.Source.Listen, "listen", cfg.Source.Listen,
+		"receive trades on network address instead of reading file, e.g. tcp://:9000 or udp://:9000")
+	flag.BoolVar(&cfg.Source.Mmap, "mmap", cfg.Source.Mmap, "map input file into memory instead of reading it")
+	flag.IntVar(&cfg.Input.Parsers, "parsers", cfg.Input.Parsers, "count of goroutines parsing trades")
+	flag.StringVar(&cfg.Input.Quarantine, "quarantine", cfg.Input.Quarantine,
+		"path to file of lines which can't be parsed into trades, they are only logged if empty")
+	flag.IntVar(&cfg.Session.StartHour, "session-start", cfg.Session.StartHour, "hour of day trading session starts at")
+	flag.IntVar(&cfg.Session.EndHour, "session-end", cfg.Session.EndHour, "hour of day trading session ends at")
+	flag.BoolVar(&cfg.Validation.PositivePrice, "positive-price", cfg.Validation.PositivePrice,
+		"reject trades with zero or negative price")
+	flag.BoolVar(&cfg.Validation.PositiveCount, "positive-count", cfg.Validation.PositiveCount,
+		"reject trades with zero or negative count")
+	flag.Float64Var(&cfg.Validation.MaxPriceJump, "max-price-jump", cfg.Validation.MaxPriceJump,
+		"reject trades which price changes by more than ratio of the last price of ticker, disabled if zero")
+	flag.StringVar(&cfg.Validation.NotBefore, "not-before", cfg.Validation.NotBefore,
+		"reject trades before RFC 3339 time, disabled if empty")
+	flag.DurationVar(&cfg.Validation.MaxAhead, "max-ahead", cfg.Validation.MaxAhead,
+		"reject trades ahead of current time by more than duration, disabled if zero")
+	flag.BoolVar(&cfg.Validation.NoDuplicates, "no-duplicates", cfg.Validation.NoDuplicates,
+		"reject duplicates of trades of the same ticker")
+	flag.DurationVar(&cfg.Validation.DuplicatesWindow, "duplicates-window", cfg.Validation.DuplicatesWindow,
+		"time before the latest trade of ticker duplicates are detected within")
+	flag.StringVar(&cfg.Checkpoint.Path, "checkpoint", cfg.Checkpoint.Path,
+		"path to checkpoint file, checkpoints are disabled if empty")
+	flag.DurationVar(&cfg.Checkpoint.Every, "checkpoint-every", cfg.Checkpoint.Every, "interval between checkpoints")
+	flag.BoolVar(&cfg.Checkpoint.Resume, "resume", cfg.Checkpoint.Resume, "continue from the last checkpoint")
+	flag.StringVar(&cfg.Outputs.Dir, "output-dir", cfg.Outputs.Dir,
+		"directory of output files, working directory if empty")
+	flag.BoolVar(&cfg.Outputs.Append, "append", cfg.Outputs.Append, "append new candles to existing output files")
+	flag.BoolVar(&cfg.Outputs.OrderFlow, "order-flow", cfg.Outputs.OrderFlow,
+		"output buy volume, sell volume and delta of candles")
+	flag.BoolVar(&cfg.Outputs.TradeTimes, "trade-times", cfg.Outputs.TradeTimes,
+		"output times of open, high, low and close trades of candles")
+	flag.DurationVar(&cfg.Outputs.PartialEvery, "partial-every", cfg.Outputs.PartialEvery,
+		"interval between writes of not closed candles to partial_ prefixed files, disabled if zero")
+	flag.BoolVar(&cfg.Outputs.HeikinAshi, "heikin-ashi", cfg.Outputs.HeikinAshi,
+		"write Heikin-Ashi candles to ha_ prefixed files")
+	flag.Var(&cfg.Outputs.Indicators, "indicators",
+		"comma separated indicators added to candles output: kind[:param...], "+
+			"where kind is sma, ema, rsi, macd, bb, atr or vwap")
+	flag.Var(&cfg.Intervals, "bars",
+		"comma separated bars: time intervals in minutes or kind:N[:TICKER=N...], "+
+			"where kind is tick, volume, dollar, range or renko")
+	flag.BoolVar(&cfg.Outputs.JSON, "json", cfg.Outputs.JSON, "also write candles to .jsonl files as JSON lines")
+	flag.IntVar(&cfg.Queues.InBuffer, "in-buffer", cfg.Queues.InBuffer, "count of trades buffered for each pipeline")
+	flag.IntVar(&cfg.Queues.OutBuffer, "out-buffer", cfg.Queues.OutBuffer,
+		"count of batches buffered between each pipeline and its writer")
+	flag.IntVar(&cfg.Queues.SinkBuffer, "sink-buffer", cfg.Queues.SinkBuffer,
+		"count of batches queued for each output sink")
+	flag.StringVar(&cfg.Queues.Overflow, "overflow", cfg.Queues.Overflow,
+		"handling of batches sent to full sink queue: block, drop the oldest or spill to disk")
+	flag.StringVar(&cfg.Queues.SpillDir, "spill-dir", cfg.Queues.SpillDir,
+		"directory of spilled batches, temporary directory is used if empty")
+	flag.DurationVar(&cfg.Queues.StatsEvery, "stats-every", cfg.Queues.StatsEvery,
+		"interval between logs of queues depth, disabled if zero")
+	flag.StringVar(&cfg.Logging.Level, "log-level", cfg.Logging.Level, "logging level: debug, info, warn or error")
+	flag.StringVar(&cfg.Logging.Format, "log-format", cfg.Logging.Format, "logging format: text or json")
+	flag.DurationVar(&cfg.ShutdownTimeout, "shutdown-timeout", cfg.ShutdownTimeout,
+		"time to wait for pipelines to complete after input end")
+	_ = flag.CommandLine.Parse(args)
+
+	if configPath != "" {
+		if err := cfg.load(configPath); err != nil {
+			logrus.Errorf("can't load config: %v", err)
+			os.Exit(1)
+		}
+
+		// flags override values of config file.
+		_ = flag.CommandLine.Parse(args)
+	}
+
+	if err := cfg.validate(); err != nil {
+		logrus.Errorf("invalid config: %v", err)
 		os.Exit(1)
 	}
 
-	wrBuilder := pipelines.WriterBuilder{}
+	logger := cfg.newLogger()
+
+	var (
+		reader      source
+		closeReader = func() {}
+	)
+
+	switch {
+	case cfg.Source.Listen != "":
+		r, err := listenNetwork(cfg.Source.Listen, logger)
+		if err != nil {
+			logger.Errorf("can't init network source: %v", err)
+			os.Exit(1)
+		}
+
+		reader, closeReader = r, r.Close
+	case serveMode:
+		r, err := files.NewFollowReader(cfg.Source.Filepath, cfg.Source.Poll, logger)
+		if err != nil {
+			logger.Errorf("can't init file reader: %v", err)
+			os.Exit(1)
+		}
+
+		reader, closeReader = r, r.Close
+	case cfg.Source.Mmap:
+		r, err := files.NewMmapReader(cfg.Source.Filepath, logger)
+		if err != nil {
+			logger.Errorf("can't init file reader: %v", err)
+			os.Exit(1)
+		}
+
+		reader, closeReader = r, r.Close
+	default:
+		r, err := files.NewReader(cfg.Source.Filepath, logger)
+		if err != nil {
+			logger.Errorf("can't init file reader: %v", err)
+			os.Exit(1)
+		}
+
+		reader = r
+	}
+
+	wrBuilder := pipelines.WriterBuilder{Dir: cfg.Outputs.Dir}
 	p := pipelines.New(reader, wrBuilder, logger)
+	p.SetParsers(cfg.Input.Parsers)
+	p.SetBuffers(cfg.Queues.InBuffer, cfg.Queues.OutBuffer)
+
+	if err := p.SetWorkingHours(cfg.Session.StartHour, cfg.Session.EndHour); err != nil {
+		logger.Errorf("can't set session hours: %v", err)
+		os.Exit(1)
+	}
+
+	// config is validated, so policy is known.
+	policy, _ := parseOverflow(cfg.Queues.Overflow)
+
+	sinkOpts := []pipelines.SinkOption{
+		pipelines.WithBuffer(cfg.Queues.SinkBuffer),
+		pipelines.WithOverflow(policy),
+		pipelines.WithSpillDir(cfg.Queues.SpillDir),
+	}
+	p.SetSinkOptions(sinkOpts...)
+	p.SetRules(cfg.rules()...)
+
+	if cfg.Input.Quarantine != "" {
+		if err := p.EnableQuarantine(cfg.Input.Quarantine); err != nil {
+			logger.Errorf("can't open quarantine file: %v", err)
+			os.Exit(1)
+		}
+	}
 
-	for _, interval := range []int{5, 30, 240} {
-		err = p.Add(interval)
+	if cfg.Checkpoint.Path != "" {
+		p.EnableCheckpoints(cfg.Checkpoint.Path, cfg.Checkpoint.Every)
+	}
+
+	if cfg.Outputs.Append {
+		p.Append()
+	}
+
+	if cfg.Checkpoint.Resume {
+		if err := p.Resume(cfg.Checkpoint.Path); err != nil {
+			logger.Errorf("can't resume from checkpoint: %v", err)
+			os.Exit(1)
+		}
+	}
+
+	if cfg.Outputs.JSON {
+		err := p.AddSink("json", pipelines.JSONWriterBuilder{Dir: cfg.Outputs.Dir}, sinkOpts...)
+		if err != nil {
+			logger.Errorf("can't add JSON sink: %v", err)
+			os.Exit(1)
+		}
+	}
+
+	specs, err := parseBars(cfg.Intervals.String())
+	if err != nil {
+		logger.Errorf("can't parse bars: %v", err)
+		os.Exit(1)
+	}
+
+	hub := server.NewHub()
+
+	inds, err := parseIndicators(cfg.Outputs.Indicators.String())
+	if err != nil {
+		logger.Errorf("can't parse indicators: %v", err)
+		os.Exit(1)
+	}
+
+	opts := pipelineOptions(inds, hub, serveMode)
+
+	for _, spec := range specs {
+		err = p.Add(spec.size, append(spec.opts, opts...)...)
 		if err != nil {
 			logger.Errorf("can't add pipeline to pipelines: %v", err)
 			os.Exit(1)
@@ -41,18 +263,76 @@ func main() {
 	// init pipeline
 	go p.Init()
 
+	if cfg.Queues.StatsEvery > 0 {
+		stopStats := make(chan struct{})
+		defer close(stopStats)
+
+		go logQueueStats(p, cfg.Queues.StatsEvery, stopStats, logger)
+	}
+
 	// send starting signal
 	p.Start <- struct{}{}
 
+	if serveMode {
+		if err = serve(p, hub, opts, closeReader, logger); err != nil {
+			logger.Errorf("serve failed: %v", err)
+			os.Exit(1)
+		}
+
+		logRuleStats(p, logger)
+		logger.Info("Successfully completed")
+
+		return
+	}
+
+	// network source is read until interrupt signal.
+	if cfg.Source.Listen != "" {
+		waitSignal()
+		closeReader()
+	}
+
 	// waiting for file reading end
 	<-p.FileDone
 
-	timeout := time.NewTicker(time.Second * timeToWait)
+	timeout := time.NewTicker(cfg.ShutdownTimeout)
 	select {
 	case <-timeout.C:
 		logger.Errorf("pipeline end timeout exceeded")
 		os.Exit(1)
 	case <-p.Done:
+		closeReader()
+		logRuleStats(p, logger)
 		logger.Info("Successfully completed")
 	}
 }
+
+// pipelineOptions returns options of all pipelines set by config.
+func pipelineOptions(inds []candles.IndicatorSpec, hub *server.Hub, serveMode bool) []pipelines.Option {
+	var opts []pipelines.Option
+
+	if cfg.Outputs.OrderFlow {
+		opts = append(opts, pipelines.WithOrderFlow())
+	}
+
+	if cfg.Outputs.TradeTimes {
+		opts = append(opts, pipelines.WithTradeTimes())
+	}
+
+	if len(inds) > 0 {
+		opts = append(opts, pipelines.WithIndicators(inds...))
+	}
+
+	if cfg.Outputs.PartialEvery > 0 {
+		opts = append(opts, pipelines.WithPartialCandles(cfg.Outputs.PartialEvery))
+	}
+
+	if cfg.Outputs.HeikinAshi {
+		opts = append(opts, pipelines.WithHeikinAshi())
+	}
+
+	if serveMode {
+		opts = append(opts, pipelines.WithCandlesHandler(hub.Publish))
+	}
+
+	return opts
+}
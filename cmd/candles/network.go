@@ -0,0 +1,22 @@
+package main
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/candles/network"
+)
+
+var errInvalidListen = errors.New("invalid listen address, expected network://address")
+
+// listenNetwork creates network source listening on address of "network://address" format.
+func listenNetwork(listen string, logger *logrus.Logger) (*network.Source, error) {
+	parts := strings.SplitN(listen, "://", 2)
+	if len(parts) != 2 {
+		return nil, errInvalidListen
+	}
+
+	return network.Listen(parts[0], parts[1], logger)
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"os"
+	"time"
+
+	"github.com/candles/pipelines"
+	"github.com/candles/query"
+)
+
+// runQuery runs query subcommand, which prints candles matching provided filters.
+func runQuery(args []string) error {
+	var (
+		q        query.Query
+		interval int
+		dir      string
+		from     string
+		to       string
+		asJSON   bool
+	)
+
+	fs := flag.NewFlagSet("query", flag.ExitOnError)
+	fs.StringVar(&dir, "dir", ".", "directory with candle files")
+	fs.StringVar(&q.Ticker, "ticker", "", "ticker of candles, all tickers if empty")
+	fs.StringVar(&q.Output, "output", "", "name of candles output, e.g. tick_100 or ha_candle_5min")
+	fs.IntVar(&interval, "interval", 5, "candles interval in minutes, used if output is empty")
+	fs.StringVar(&from, "from", "", "RFC3339 start of time range, inclusive")
+	fs.StringVar(&to, "to", "", "RFC3339 end of time range, exclusive")
+	fs.BoolVar(&asJSON, "json", false, "print candles as JSON lines")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if q.Output == "" {
+		q.Output = pipelines.IntervalOutput(interval)
+	}
+
+	var err error
+
+	if q.From, err = parseQueryTime(from); err != nil {
+		return err
+	}
+
+	if q.To, err = parseQueryTime(to); err != nil {
+		return err
+	}
+
+	cs, err := query.NewStore(dir).Candles(q)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	enc := json.NewEncoder(w)
+
+	for i := range cs {
+		if asJSON {
+			err = enc.Encode(&cs[i])
+		} else {
+			_, err = w.WriteString(cs[i].String() + "\n")
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return w.Flush()
+}
+
+// parseQueryTime parses RFC3339 time, empty value is parsed as zero time.
+func parseQueryTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+
+	return time.Parse(time.RFC3339, s)
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"errors"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/candles/pipelines"
+)
+
+var errInvalidOverflow = errors.New("invalid overflow policy")
+
+// parseOverflow parses overflow policy of sink queues: block, drop or spill.
+func parseOverflow(s string) (pipelines.OverflowPolicy, error) {
+	switch s {
+	case "block":
+		return pipelines.BlockOnFull, nil
+	case "drop":
+		return pipelines.DropOldest, nil
+	case "spill":
+		return pipelines.SpillToDisk, nil
+	default:
+		return 0, errInvalidOverflow
+	}
+}
+
+// logQueueStats logs depth of pipelines queues and counts of trades rejected by validation
+// every interval until done is closed.
+func logQueueStats(p *pipelines.Pipelines, every time.Duration, done <-chan struct{}, logger *logrus.Logger) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			for _, st := range p.QueueStats() {
+				logger.WithFields(logrus.Fields{
+					"depth":    st.Depth,
+					"capacity": st.Capacity,
+					"dropped":  st.Dropped,
+					"spilled":  st.Spilled,
+				}).Infof("Queue %s", st.Name)
+			}
+
+			logRuleStats(p, logger)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/candles/pipelines"
+)
+
+// rules returns validation rules of trades set by config.
+// Config has to be validated.
+func (c *config) rules() []pipelines.Rule {
+	v := c.Validation
+
+	var rules []pipelines.Rule
+
+	if v.PositivePrice {
+		rules = append(rules, pipelines.PositivePrice())
+	}
+
+	if v.PositiveCount {
+		rules = append(rules, pipelines.PositiveCount())
+	}
+
+	if v.NotBefore != "" || v.MaxAhead > 0 {
+		// config is validated, so time is parsed.
+		notBefore, _ := parseNotBefore(v.NotBefore)
+
+		maxAhead := v.MaxAhead
+		if maxAhead == 0 {
+			maxAhead = -1
+		}
+
+		rules = append(rules, pipelines.TimeBounds(notBefore, maxAhead))
+	}
+
+	if v.NoDuplicates {
+		rules = append(rules, pipelines.NoDuplicates(v.DuplicatesWindow))
+	}
+
+	if v.MaxPriceJump > 0 {
+		rules = append(rules, pipelines.MaxPriceJump(v.MaxPriceJump))
+	}
+
+	return rules
+}
+
+// parseNotBefore parses RFC 3339 lower bound of trades time, zero time if s is empty.
+func parseNotBefore(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+
+	return time.Parse(time.RFC3339, s)
+}
+
+// logRuleStats logs counts of trades rejected by each validation rule.
+func logRuleStats(p *pipelines.Pipelines, logger *logrus.Logger) {
+	for _, st := range p.RuleStats() {
+		logger.WithField("rejected", st.Rejected).Infof("Rule %s", st.Rule)
+	}
+}
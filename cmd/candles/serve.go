@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/candles/pipelines"
+	"github.com/candles/query"
+	"github.com/candles/server"
+)
+
+// serve serves HTTP API until interrupt signal,
+// then stops reading trades and waits for pipelines to complete.
+// Interval pipelines added via API are created with provided options.
+func serve(
+	p *pipelines.Pipelines,
+	hub *server.Hub,
+	opts []pipelines.Option,
+	stopReader func(),
+	logger *logrus.Logger,
+) error {
+	s := server.New(query.NewStore(cfg.Outputs.Dir), hub, logger)
+	s.SetController(intervals{p: p, opts: opts})
+	s.SetOutputs(p.Outputs)
+
+	srv := &http.Server{
+		Addr:    cfg.Server.Addr,
+		Handler: s.Handler(),
+	}
+
+	errs := make(chan error, 1)
+
+	go func() {
+		errs <- srv.ListenAndServe()
+	}()
+
+	logger.Infof("Serving candles on %s", cfg.Server.Addr)
+
+	select {
+	case err := <-errs:
+		return err
+	case <-signals():
+	}
+
+	stopReader()
+	<-p.FileDone
+	<-p.Done
+
+	// streams are finished, so shutdown doesn't wait for them.
+	hub.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	return srv.Shutdown(ctx)
+}
+
+// intervals adds and removes interval pipelines of served candles.
+type intervals struct {
+	p    *pipelines.Pipelines
+	opts []pipelines.Option
+}
+
+// AddInterval adds pipeline with provided interval in minutes.
+func (c intervals) AddInterval(interval int) error {
+	return c.p.Add(interval, c.opts...)
+}
+
+// RemoveInterval removes pipeline with provided interval in minutes.
+func (c intervals) RemoveInterval(interval int) error {
+	return c.p.Remove(pipelines.IntervalOutput(interval))
+}
+
+// signals returns chan receiving interrupt and termination signals.
+func signals() <-chan os.Signal {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+
+	return c
+}
+
+// waitSignal waits for interrupt or termination signal.
+func waitSignal() {
+	<-signals()
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/candles/pipelines/candles"
+)
+
+var (
+	errInvalidTrades = errors.New("file contains invalid trades")
+	errLineTooLong   = errors.New("line is too long")
+)
+
+// maxLineSize is the maximum size of line read by subcommands.
+const maxLineSize = 1024 * 1024
+
+// runValidate runs validate subcommand, which prints numbers and errors of all invalid trade lines.
+func runValidate(args []string) error {
+	var path string
+
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	fs.StringVar(&path, "filepath", "trades.csv", "path to file with trades")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+
+	var lines, invalid int
+
+	err := readLines(path, func(n int, line string, err error) error {
+		lines = n
+
+		if err == nil {
+			_, err = candles.TradeFromString(line)
+		}
+
+		if err != nil {
+			invalid++
+
+			_, err = fmt.Fprintf(w, "line %d: %v\n", n, err)
+
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if _, err = fmt.Fprintf(w, "%d of %d lines are invalid\n", invalid, lines); err != nil {
+		return err
+	}
+
+	if err = w.Flush(); err != nil {
+		return err
+	}
+
+	if invalid > 0 {
+		return errInvalidTrades
+	}
+
+	return nil
+}
+
+// readLines calls f with number and value of each line of file, starting from 1.
+// Lines longer than maxLineSize are skipped, f is called with their numbers and errLineTooLong.
+// Reading stops on the first error returned by f.
+func readLines(path string, f func(n int, line string, err error) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	var (
+		r = bufio.NewReader(file)
+		// pending accumulates line longer than read buffer.
+		pending []byte
+		tooLong bool
+	)
+
+	for n := 1; ; {
+		chunk, err := r.ReadSlice('\n')
+
+		if tooLong || len(pending)+len(chunk) > maxLineSize {
+			pending, tooLong = pending[:0], true
+		} else {
+			pending = append(pending, chunk...)
+		}
+
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+
+		if err != nil && err != io.EOF {
+			return err
+		}
+
+		// the last line may have no line break.
+		if tooLong {
+			if ferr := f(n, "", errLineTooLong); ferr != nil {
+				return ferr
+			}
+
+			n++
+		} else if len(pending) > 0 {
+			line := bytes.TrimSuffix(bytes.TrimSuffix(pending, []byte("\n")), []byte("\r"))
+			if ferr := f(n, string(line), nil); ferr != nil {
+				return ferr
+			}
+
+			n++
+		}
+
+		pending, tooLong = pending[:0], false
+
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadLines(t *testing.T) {
+	dir, err := ioutil.TempDir("", "validate")
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "trades.csv")
+
+	data := "A,10,1,2019-01-30 11:00:00\n" +
+		strings.Repeat("x", maxLineSize+1) + "\n" +
+		"\r\n" +
+		"A,10,1,2019-01-30 11:00:01"
+	assert.NoError(t, ioutil.WriteFile(path, []byte(data), 0600))
+
+	type line struct {
+		n    int
+		line string
+		err  error
+	}
+
+	var got []line
+
+	assert.NoError(t, readLines(path, func(n int, l string, err error) error {
+		got = append(got, line{n, l, err})
+		return nil
+	}))
+
+	assert.Equal(t, []line{
+		{1, "A,10,1,2019-01-30 11:00:00", nil},
+		{2, "", errLineTooLong},
+		{3, "", nil},
+		{4, "A,10,1,2019-01-30 11:00:01", nil},
+	}, got)
+
+	// all lines are checked.
+	assert.Equal(t, errInvalidTrades, runValidate([]string{"-filepath", path}))
+}
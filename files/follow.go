@@ -0,0 +1,149 @@
+package files
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// FollowReader represents file reader, which keeps reading lines
+// appended to file until it is closed.
+type FollowReader struct {
+	fileName string
+	file     *os.File
+	poll     time.Duration
+	fileData chan Batch
+	start    chan struct{}
+	stop     chan struct{}
+	stopOnce sync.Once
+
+	l *logrus.Logger
+}
+
+// NewFollowReader creates new FollowReader,
+// which checks for new lines every poll interval after reaching end of file.
+func NewFollowReader(filename string, poll time.Duration, logger *logrus.Logger) (*FollowReader, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FollowReader{
+		fileName: filename,
+		file:     f,
+		poll:     poll,
+		fileData: make(chan Batch),
+		start:    make(chan struct{}),
+		stop:     make(chan struct{}),
+		l:        logger,
+	}, nil
+}
+
+// C returns chan which data would be written to.
+func (r *FollowReader) C() chan Batch {
+	return r.fileData
+}
+
+// StartChan returns chan to receive a start signal.
+func (r *FollowReader) StartChan() chan struct{} {
+	return r.start
+}
+
+// SetOffset sets the offset reading starts from.
+// Must be called before the start signal.
+func (r *FollowReader) SetOffset(offset int64) error {
+	_, err := r.file.Seek(offset, io.SeekStart)
+	return err
+}
+
+// Init starts writing data to output chan.
+// Lines are sent as soon as end of file is reached,
+// incomplete last line is kept until its line break is written.
+// Output chan is closed after Close call.
+func (r *FollowReader) Init() {
+	defer close(r.fileData)
+	defer r.file.Close()
+
+	select {
+	case <-r.start:
+	case <-r.stop:
+		return
+	}
+
+	offset, err := r.file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		r.l.Errorf("can't get file offset: %v", err)
+	}
+
+	lines, err := countLines(r.file, offset)
+	if err != nil {
+		r.l.Errorf("can't count lines before offset: %v", err)
+	}
+
+	var (
+		br      = bufio.NewReaderSize(r.file, batchBufSize)
+		b       = newBatch()
+		pending []byte
+	)
+
+	for {
+		line, err := br.ReadSlice('\n')
+		pending = append(pending, line...)
+
+		switch err {
+		case nil:
+			offset += int64(len(pending))
+			lines++
+
+			if len(b.Lines) == 0 {
+				b.Source, b.Line = r.fileName, lines
+			}
+
+			b.add(bytes.TrimSuffix(bytes.TrimSuffix(pending, []byte("\n")), []byte("\r")))
+			b.Offset = offset
+			pending = pending[:0]
+
+			if len(b.Lines) == batchLines && !r.send(&b) {
+				return
+			}
+		case bufio.ErrBufferFull:
+		case io.EOF:
+			if len(b.Lines) > 0 && !r.send(&b) {
+				return
+			}
+
+			select {
+			case <-r.stop:
+				return
+			case <-time.After(r.poll):
+			}
+		default:
+			r.l.Errorf("read error: %v", err)
+			return
+		}
+	}
+}
+
+// send sends batch to output chan and replaces it with new one.
+// Reports false if reader is closed.
+func (r *FollowReader) send(b *Batch) bool {
+	select {
+	case r.fileData <- *b:
+		*b = newBatch()
+		return true
+	case <-r.stop:
+		return false
+	}
+}
+
+// Close makes reader stop following file and close output chan.
+func (r *FollowReader) Close() {
+	r.stopOnce.Do(func() {
+		close(r.stop)
+	})
+}
@@ -0,0 +1,46 @@
+package files_test
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/candles/files"
+)
+
+func TestFollowReader_Init(t *testing.T) {
+	f, err := ioutil.TempFile("", "trades")
+	assert.NoError(t, err)
+
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	_, err = f.WriteString("one\ntwo\nthr")
+	assert.NoError(t, err)
+
+	r, err := files.NewFollowReader(f.Name(), time.Millisecond, logrus.New())
+	assert.NoError(t, err)
+
+	go r.Init()
+	r.StartChan() <- struct{}{}
+
+	b := <-r.C()
+	assert.Equal(t, [][]byte{[]byte("one"), []byte("two")}, b.Lines)
+	assert.Equal(t, int64(8), b.Offset)
+
+	_, err = f.WriteString("ee\r\nfour\n")
+	assert.NoError(t, err)
+
+	b = <-r.C()
+	assert.Equal(t, [][]byte{[]byte("three"), []byte("four")}, b.Lines)
+	assert.Equal(t, int64(20), b.Offset)
+
+	r.Close()
+
+	for range r.C() {
+	}
+}
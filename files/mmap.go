@@ -0,0 +1,143 @@
+package files
+
+import (
+	"bytes"
+	"errors"
+	"os"
+
+	"github.com/sirupsen/logrus"
+)
+
+const chunkSize = 256 * 1024
+
+var errInvalidOffset = errors.New("offset is out of file range")
+
+// MmapReader represents file reader, which maps the whole file into memory
+// and sends it split into chunks of complete lines.
+// Lines of sent batches point to the mapped memory,
+// so Close has to be called only after all batches are processed.
+type MmapReader struct {
+	fileName  string
+	data      []byte
+	offset    int64
+	chunkSize int
+	fileData  chan Batch
+	start     chan struct{}
+
+	l *logrus.Logger
+}
+
+// NewMmapReader creates new memory-mapped file reader.
+func NewMmapReader(filename string, logger *logrus.Logger) (*MmapReader, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	data, err := mmapFile(f)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MmapReader{
+		fileName:  filename,
+		data:      data,
+		chunkSize: chunkSize,
+		fileData:  make(chan Batch),
+		start:     make(chan struct{}),
+		l:         logger,
+	}, nil
+}
+
+// C returns chan which data would be written to.
+func (r *MmapReader) C() chan Batch {
+	return r.fileData
+}
+
+// Init waits for start signal and starts writing data to output chan.
+func (r *MmapReader) Init() {
+	<-r.start
+
+	line := int64(bytes.Count(r.data[:r.offset], []byte{'\n'})) + 1
+
+	for offset := r.offset; offset < int64(len(r.data)); {
+		data := r.data[offset:]
+		n := chunkEnd(data, r.chunkSize)
+		offset += int64(n)
+
+		b := splitLines(data[:n])
+		b.Offset = offset
+		b.Source, b.Line = r.fileName, line
+		line += int64(len(b.Lines))
+		r.fileData <- b
+	}
+
+	close(r.fileData)
+}
+
+// StartChan returns chan to receive a start signal.
+func (r *MmapReader) StartChan() chan struct{} {
+	return r.start
+}
+
+// SetOffset sets the offset reading starts from.
+// Must be called before the start signal.
+func (r *MmapReader) SetOffset(offset int64) error {
+	if offset < 0 || offset > int64(len(r.data)) {
+		return errInvalidOffset
+	}
+
+	r.offset = offset
+
+	return nil
+}
+
+// Close unmaps the file from memory.
+func (r *MmapReader) Close() {
+	if err := munmap(r.data); err != nil {
+		r.l.Errorf("can't unmap file %s: %v", r.fileName, err)
+	}
+}
+
+// chunkEnd returns length of the chunk from the beginning of data,
+// which is at least size bytes long and ends with a line break.
+func chunkEnd(data []byte, size int) int {
+	if len(data) <= size {
+		return len(data)
+	}
+
+	i := bytes.IndexByte(data[size:], '\n')
+	if i < 0 {
+		return len(data)
+	}
+
+	return size + i + 1
+}
+
+// splitLines splits chunk into lines the same way bufio.ScanLines does,
+// without copying them.
+func splitLines(chunk []byte) Batch {
+	b := Batch{
+		Lines: make([][]byte, 0, bytes.Count(chunk, []byte{'\n'})+1),
+	}
+
+	for len(chunk) > 0 {
+		var line []byte
+
+		if i := bytes.IndexByte(chunk, '\n'); i >= 0 {
+			line, chunk = chunk[:i], chunk[i+1:]
+		} else {
+			line, chunk = chunk, nil
+		}
+
+		if n := len(line); n > 0 && line[n-1] == '\r' {
+			line = line[:n-1]
+		}
+
+		b.Lines = append(b.Lines, line)
+	}
+
+	return b
+}
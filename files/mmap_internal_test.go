@@ -0,0 +1,84 @@
+package files
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMmapReader_Internal_chunkEnd(t *testing.T) {
+	type args struct {
+		data string
+		size int
+	}
+
+	tests := []struct {
+		name string
+		args args
+		want int
+	}{
+		{
+			name: "data shorter than chunk",
+			args: args{data: "one\ntwo\n", size: 100},
+			want: 8,
+		},
+		{
+			name: "chunk ends at line break",
+			args: args{data: "one\ntwo\nthree\n", size: 2},
+			want: 4,
+		},
+		{
+			name: "size points to line break",
+			args: args{data: "one\ntwo\nthree\n", size: 3},
+			want: 4,
+		},
+		{
+			name: "last line without line break",
+			args: args{data: "one\ntwo", size: 5},
+			want: 7,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.want, chunkEnd([]byte(test.args.data), test.args.size))
+		})
+	}
+}
+
+func TestMmapReader_Internal_splitLines(t *testing.T) {
+	tests := []struct {
+		name  string
+		chunk string
+		want  []string
+	}{
+		{
+			name:  "multiple lines",
+			chunk: "one\ntwo\nthree\n",
+			want:  []string{"one", "two", "three"},
+		},
+		{
+			name:  "carriage returns and empty lines",
+			chunk: "one\r\n\ntwo",
+			want:  []string{"one", "", "two"},
+		},
+		{
+			name:  "empty chunk",
+			chunk: "",
+			want:  []string{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b := splitLines([]byte(test.chunk))
+
+			got := make([]string, 0, len(b.Lines))
+			for _, l := range b.Lines {
+				got = append(got, string(l))
+			}
+
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
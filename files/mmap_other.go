@@ -0,0 +1,20 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package files
+
+import (
+	"io/ioutil"
+	"os"
+)
+
+// mmapFile reads the whole file into memory,
+// as memory mapping is not supported on the platform.
+func mmapFile(f *os.File) ([]byte, error) {
+	return ioutil.ReadAll(f)
+}
+
+// munmap does nothing, memory is released by garbage collector.
+func munmap([]byte) error {
+	return nil
+}
@@ -0,0 +1,69 @@
+package files_test
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/candles/files"
+)
+
+func TestMmapReader_Init(t *testing.T) {
+	longLine := strings.Repeat("x", 1024*1024)
+
+	tests := []struct {
+		name string
+		data string
+		want []string
+	}{
+		{
+			name: "success, multiple lines",
+			data: "one\ntwo\nthree\n",
+			want: []string{"one", "two", "three"},
+		},
+		{
+			name: "success, line longer than scanner limit",
+			data: "one\n" + longLine + "\ntwo",
+			want: []string{"one", longLine, "two"},
+		},
+		{
+			name: "empty file",
+			data: "",
+			want: []string{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f, err := ioutil.TempFile("", "trades")
+			assert.NoError(t, err)
+
+			defer os.Remove(f.Name())
+
+			_, err = f.WriteString(test.data)
+			assert.NoError(t, err)
+			assert.NoError(t, f.Close())
+
+			r, err := files.NewMmapReader(f.Name(), logrus.New())
+			assert.NoError(t, err)
+
+			defer r.Close()
+
+			go r.Init()
+			r.StartChan() <- struct{}{}
+
+			got := make([]string, 0, len(test.want))
+			for b := range r.C() {
+				for _, l := range b.Lines {
+					got = append(got, string(l))
+				}
+			}
+
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
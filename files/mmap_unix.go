@@ -0,0 +1,32 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package files
+
+import (
+	"os"
+	"syscall"
+)
+
+// mmapFile maps the whole file into memory in read-only mode.
+func mmapFile(f *os.File) ([]byte, error) {
+	st, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	if st.Size() == 0 {
+		return nil, nil
+	}
+
+	return syscall.Mmap(int(f.Fd()), 0, int(st.Size()), syscall.PROT_READ, syscall.MAP_SHARED)
+}
+
+// munmap unmaps memory mapped by mmapFile.
+func munmap(data []byte) error {
+	if len(data) == 0 {
+		return nil
+	}
+
+	return syscall.Munmap(data)
+}
@@ -2,16 +2,37 @@ package files
 
 import (
 	"bufio"
+	"bytes"
+	"io"
 	"os"
 
 	"github.com/sirupsen/logrus"
 )
 
+const (
+	batchLines   = 1024
+	batchBufSize = 64 * 1024
+)
+
+// Batch contains consecutive lines read from file.
+type Batch struct {
+	Lines [][]byte
+	// Offset is the input offset right after the last line of the batch.
+	Offset int64
+	// Source is the name of input lines are read from, e.g. file path.
+	Source string
+	// Line is the number of the first line of the batch in input starting from 1,
+	// or zero if it is unknown.
+	Line int64
+
+	buf []byte
+}
+
 // Reader represents file reader.
 type Reader struct {
 	fileName string
 	file     *os.File
-	fileData chan string
+	fileData chan Batch
 	start    chan struct{}
 
 	l *logrus.Logger
@@ -27,14 +48,14 @@ func NewReader(filename string, logger *logrus.Logger) (Reader, error) {
 	return Reader{
 		fileName: filename,
 		file:     f,
-		fileData: make(chan string),
+		fileData: make(chan Batch),
 		start:    make(chan struct{}),
 		l:        logger,
 	}, nil
 }
 
 // C returns chan which data would be written to.
-func (r Reader) C() chan string {
+func (r Reader) C() chan Batch {
 	return r.fileData
 }
 
@@ -42,14 +63,62 @@ func (r Reader) C() chan string {
 func (r Reader) Init() {
 	<-r.start
 
-	s := bufio.NewScanner(r.file)
+	offset, err := r.file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		r.l.Errorf("can't get file offset: %v", err)
+	}
 
-	for s.Scan() {
-		r.fileData <- s.Text()
+	line, err := countLines(r.file, offset)
+	if err != nil {
+		r.l.Errorf("can't count lines before offset: %v", err)
 	}
 
-	if err := s.Err(); err != nil {
-		r.l.Errorf("scan error: %v", err)
+	var (
+		br = bufio.NewReaderSize(r.file, batchBufSize)
+		b  = newBatch()
+		// pending accumulates line longer than read buffer.
+		pending []byte
+	)
+
+	for {
+		chunk, err := br.ReadSlice('\n')
+		pending = append(pending, chunk...)
+
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+
+		if err != nil && err != io.EOF {
+			r.l.Errorf("read error: %v", err)
+			break
+		}
+
+		// the last line may have no line break.
+		if len(pending) > 0 {
+			offset += int64(len(pending))
+			line++
+
+			if len(b.Lines) == 0 {
+				b.Source, b.Line = r.fileName, line
+			}
+
+			b.add(bytes.TrimSuffix(bytes.TrimSuffix(pending, []byte("\n")), []byte("\r")))
+			b.Offset = offset
+			pending = pending[:0]
+
+			if len(b.Lines) == batchLines {
+				r.fileData <- b
+				b = newBatch()
+			}
+		}
+
+		if err == io.EOF {
+			break
+		}
+	}
+
+	if len(b.Lines) > 0 {
+		r.fileData <- b
 	}
 
 	close(r.fileData)
@@ -59,3 +128,127 @@ func (r Reader) Init() {
 func (r Reader) StartChan() chan struct{} {
 	return r.start
 }
+
+// SetOffset sets the offset reading starts from.
+// Must be called before the start signal.
+func (r Reader) SetOffset(offset int64) error {
+	_, err := r.file.Seek(offset, io.SeekStart)
+	return err
+}
+
+// countLines returns count of line breaks in the first size bytes of file.
+func countLines(f *os.File, size int64) (int64, error) {
+	var (
+		n   int64
+		buf = make([]byte, batchBufSize)
+	)
+
+	for pos := int64(0); pos < size; {
+		chunk := buf
+		if rest := size - pos; rest < int64(len(chunk)) {
+			chunk = chunk[:rest]
+		}
+
+		read, err := f.ReadAt(chunk, pos)
+		n += int64(bytes.Count(chunk[:read], []byte{'\n'}))
+		pos += int64(read)
+
+		if err != nil {
+			return n, err
+		}
+	}
+
+	return n, nil
+}
+
+// newBatch creates empty Batch with preallocated buffers.
+func newBatch() Batch {
+	return Batch{
+		Lines: make([][]byte, 0, batchLines),
+	}
+}
+
+// add copies line to the batch.
+// Lines share a single buffer, which is reallocated only when exceeded.
+func (b *Batch) add(line []byte) {
+	if cap(b.buf)-len(b.buf) < len(line) {
+		size := batchBufSize
+		if len(line) > size {
+			size = len(line)
+		}
+
+		b.buf = make([]byte, 0, size)
+	}
+
+	start := len(b.buf)
+	b.buf = append(b.buf, line...)
+	b.Lines = append(b.Lines, b.buf[start:len(b.buf):len(b.buf)])
+}
+
+// LastLines reads file backwards and returns consecutive lines from the end of file,
+// while keep reports true for them, along with the offset the first returned line starts at.
+// Lines are returned in file order without line breaks.
+func LastLines(path string, keep func(line string) bool) ([]string, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	defer f.Close()
+
+	size, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var (
+		lines []string
+		// buf contains file data from pos till offset.
+		buf    []byte
+		pos    = size
+		offset = size
+	)
+
+	for offset > 0 {
+		start := 0
+		if len(buf) > 0 {
+			// line break of the line itself is not a line start.
+			start = bytes.LastIndexByte(buf[:len(buf)-1], '\n') + 1
+		}
+
+		if start == 0 && pos > 0 {
+			buf, pos, err = readBefore(f, buf, pos)
+			if err != nil {
+				return nil, 0, err
+			}
+
+			continue
+		}
+
+		line := string(bytes.TrimRight(buf[start:], "\r\n"))
+		if !keep(line) {
+			break
+		}
+
+		lines = append([]string{line}, lines...)
+		offset = pos + int64(start)
+		buf = buf[:start]
+	}
+
+	return lines, offset, nil
+}
+
+// readBefore reads block of data before pos and prepends it to buf.
+func readBefore(f *os.File, buf []byte, pos int64) ([]byte, int64, error) {
+	n := int64(batchBufSize)
+	if n > pos {
+		n = pos
+	}
+
+	block := make([]byte, n, n+int64(len(buf)))
+	if _, err := f.ReadAt(block, pos-n); err != nil {
+		return nil, 0, err
+	}
+
+	return append(block, buf...), pos - n, nil
+}
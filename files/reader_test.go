@@ -0,0 +1,206 @@
+package files_test
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/candles/files"
+)
+
+func TestLastLines(t *testing.T) {
+	longLine := strings.Repeat("x", 100*1024)
+
+	type args struct {
+		data string
+		keep func(line string) bool
+	}
+
+	tests := []struct {
+		name       string
+		args       args
+		want       []string
+		wantOffset int64
+	}{
+		{
+			name: "success, lines with common prefix",
+			args: args{
+				data: "a1\nb1\nb2\n",
+				keep: func(line string) bool { return strings.HasPrefix(line, "b") },
+			},
+			want:       []string{"b1", "b2"},
+			wantOffset: 3,
+		},
+		{
+			name: "success, no trailing line break",
+			args: args{
+				data: "a1\r\nb1\r\nb2",
+				keep: func(line string) bool { return strings.HasPrefix(line, "b") },
+			},
+			want:       []string{"b1", "b2"},
+			wantOffset: 4,
+		},
+		{
+			name: "success, all lines kept",
+			args: args{
+				data: "b0\n" + longLine + "\nb1\n",
+				keep: func(line string) bool { return true },
+			},
+			want:       []string{"b0", longLine, "b1"},
+			wantOffset: 0,
+		},
+		{
+			name: "success, long lines",
+			args: args{
+				data: longLine + "\nb1\n",
+				keep: func(line string) bool { return strings.HasPrefix(line, "b") },
+			},
+			want:       []string{"b1"},
+			wantOffset: int64(len(longLine) + 1),
+		},
+		{
+			name: "nothing kept",
+			args: args{
+				data: "a1\na2\n",
+				keep: func(line string) bool { return false },
+			},
+			want:       nil,
+			wantOffset: 6,
+		},
+		{
+			name: "empty file",
+			args: args{
+				data: "",
+				keep: func(line string) bool { return true },
+			},
+			want:       nil,
+			wantOffset: 0,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f, err := ioutil.TempFile("", "candles")
+			assert.NoError(t, err)
+
+			defer os.Remove(f.Name())
+
+			_, err = f.WriteString(test.args.data)
+			assert.NoError(t, err)
+			assert.NoError(t, f.Close())
+
+			got, offset, err := files.LastLines(f.Name(), test.args.keep)
+			assert.NoError(t, err)
+			assert.Equal(t, test.want, got)
+			assert.Equal(t, test.wantOffset, offset)
+		})
+	}
+}
+
+func TestReaders_lineNumbers(t *testing.T) {
+	const lines = 3000
+
+	f, err := ioutil.TempFile("", "trades")
+	assert.NoError(t, err)
+
+	defer os.Remove(f.Name())
+
+	var (
+		data   strings.Builder
+		offset int
+	)
+
+	for i := 1; i <= lines; i++ {
+		if i == 5 {
+			offset = data.Len()
+		}
+
+		data.WriteString(strconv.Itoa(i) + "\n")
+	}
+
+	_, err = f.WriteString(data.String())
+	assert.NoError(t, err)
+	assert.NoError(t, f.Close())
+
+	type reader interface {
+		C() chan files.Batch
+		StartChan() chan struct{}
+		SetOffset(offset int64) error
+		Init()
+	}
+
+	r, err := files.NewReader(f.Name(), logrus.New())
+	assert.NoError(t, err)
+
+	mr, err := files.NewMmapReader(f.Name(), logrus.New())
+	assert.NoError(t, err)
+
+	defer mr.Close()
+
+	for name, r := range map[string]reader{"reader": r, "mmap reader": mr} {
+		t.Run(name, func(t *testing.T) {
+			assert.NoError(t, r.SetOffset(int64(offset)))
+
+			go r.Init()
+			r.StartChan() <- struct{}{}
+
+			want := 5
+			for b := range r.C() {
+				assert.Equal(t, f.Name(), b.Source)
+
+				for i, l := range b.Lines {
+					assert.Equal(t, strconv.Itoa(want), string(l))
+					assert.Equal(t, int64(want), b.Line+int64(i))
+					want++
+				}
+			}
+
+			assert.Equal(t, lines+1, want)
+		})
+	}
+}
+
+func TestReader_longLines(t *testing.T) {
+	long := strings.Repeat("x", 200*1024)
+	want := []string{"first", long, "", "crlf", long, "last"}
+
+	f, err := ioutil.TempFile("", "trades")
+	assert.NoError(t, err)
+
+	defer os.Remove(f.Name())
+
+	_, err = f.WriteString("first\n" + long + "\n\ncrlf\r\n" + long + "\nlast")
+	assert.NoError(t, err)
+
+	size, err := f.Seek(0, io.SeekCurrent)
+	assert.NoError(t, err)
+	assert.NoError(t, f.Close())
+
+	r, err := files.NewReader(f.Name(), logrus.New())
+	assert.NoError(t, err)
+
+	go r.Init()
+	r.StartChan() <- struct{}{}
+
+	var (
+		got    []string
+		offset int64
+	)
+
+	for b := range r.C() {
+		for _, l := range b.Lines {
+			got = append(got, string(l))
+		}
+
+		offset = b.Offset
+	}
+
+	assert.Equal(t, want, got)
+	assert.Equal(t, size, offset)
+}
@@ -2,6 +2,7 @@ package files
 
 import (
 	"errors"
+	"io"
 	"os"
 	"sync"
 )
@@ -24,6 +25,27 @@ func NewWriter(path string) (*Writer, error) {
 	return &Writer{Mutex: &sync.Mutex{}, file: f}, nil
 }
 
+// OpenWriter opens existing file for writing,
+// discarding all data after size bytes.
+func OpenWriter(path string, size int64) (*Writer, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = f.Truncate(size); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+
+	if _, err = f.Seek(size, io.SeekStart); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+
+	return &Writer{Mutex: &sync.Mutex{}, file: f}, nil
+}
+
 // Close closes the file inside a Writer.
 func (w *Writer) Close() {
 	w.Lock()
@@ -48,3 +70,16 @@ func (w *Writer) WriteString(data string) error {
 
 	return nil
 }
+
+// Sync commits written data to stable storage
+// and returns size of written data.
+func (w *Writer) Sync() (int64, error) {
+	w.Lock()
+	defer w.Unlock()
+
+	if err := w.file.Sync(); err != nil {
+		return 0, err
+	}
+
+	return w.file.Seek(0, io.SeekCurrent)
+}
@@ -0,0 +1,135 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrInvalidOffset is returned when setting offset out of topic messages.
+var ErrInvalidOffset = errors.New("invalid offset")
+
+// Broker is an in-process stand-in of Kafka broker with single partition topics.
+// Broker implements Producer, its consumers implement Consumer.
+type Broker struct {
+	mu      sync.Mutex
+	topics  map[string][]Message
+	commits map[string]int64
+	// changed is closed and replaced when messages are produced.
+	changed chan struct{}
+}
+
+// NewBroker creates new Broker.
+func NewBroker() *Broker {
+	return &Broker{
+		topics:  make(map[string][]Message),
+		commits: make(map[string]int64),
+		changed: make(chan struct{}),
+	}
+}
+
+// Produce appends message to its topic.
+func (b *Broker) Produce(_ context.Context, m Message) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	m.Offset = int64(len(b.topics[m.Topic]))
+	b.topics[m.Topic] = append(b.topics[m.Topic], m)
+
+	close(b.changed)
+	b.changed = make(chan struct{})
+
+	return nil
+}
+
+// Flush does nothing, as messages are produced synchronously.
+func (b *Broker) Flush(context.Context) error {
+	return nil
+}
+
+// Messages returns all messages of topic.
+func (b *Broker) Messages(topic string) []Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return append([]Message(nil), b.topics[topic]...)
+}
+
+// Committed returns offset committed by consumer group of topic.
+func (b *Broker) Committed(topic, group string) int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.commits[commitKey(topic, group)]
+}
+
+// Consumer creates consumer of topic, which starts from offset committed by its group.
+func (b *Broker) Consumer(topic, group string) *BrokerConsumer {
+	return &BrokerConsumer{
+		b:      b,
+		topic:  topic,
+		group:  group,
+		offset: b.Committed(topic, group),
+	}
+}
+
+// BrokerConsumer consumes messages of Broker topic.
+type BrokerConsumer struct {
+	b      *Broker
+	topic  string
+	group  string
+	offset int64
+}
+
+// Fetch returns messages after the last fetched one,
+// blocking until at least one is available or ctx is done.
+func (c *BrokerConsumer) Fetch(ctx context.Context) ([]Message, error) {
+	for {
+		c.b.mu.Lock()
+		msgs := c.b.topics[c.topic]
+		changed := c.b.changed
+		c.b.mu.Unlock()
+
+		if int64(len(msgs)) > c.offset {
+			out := append([]Message(nil), msgs[c.offset:]...)
+			c.offset = int64(len(msgs))
+
+			return out, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+// SetOffset makes next Fetch return messages starting from offset.
+func (c *BrokerConsumer) SetOffset(offset int64) error {
+	c.b.mu.Lock()
+	defer c.b.mu.Unlock()
+
+	if offset < 0 || offset > int64(len(c.b.topics[c.topic])) {
+		return ErrInvalidOffset
+	}
+
+	c.offset = offset
+
+	return nil
+}
+
+// Commit commits offset of consumer group.
+func (c *BrokerConsumer) Commit(_ context.Context, offset int64) error {
+	c.b.mu.Lock()
+	defer c.b.mu.Unlock()
+
+	c.b.commits[commitKey(c.topic, c.group)] = offset
+
+	return nil
+}
+
+// commitKey returns key of committed offset of consumer group of topic.
+func commitKey(topic, group string) string {
+	return group + "/" + topic
+}
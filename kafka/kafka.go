@@ -0,0 +1,36 @@
+// Package kafka consumes trades from and publishes candles to Kafka-compatible brokers.
+// Clients are plugged in by Consumer and Producer interfaces,
+// Broker is an in-process implementation of them.
+package kafka
+
+import (
+	"context"
+)
+
+// Message is a record of topic.
+// Topics are expected to have a single partition, so trades are consumed in order.
+type Message struct {
+	Topic  string
+	Offset int64
+	Key    []byte
+	Value  []byte
+}
+
+// Consumer consumes messages of a single topic.
+type Consumer interface {
+	// Fetch returns next messages, blocking until at least one is available or ctx is done.
+	Fetch(ctx context.Context) ([]Message, error)
+	// SetOffset makes next Fetch return messages starting from offset.
+	SetOffset(offset int64) error
+	// Commit commits offset of the next message to consume,
+	// so consumption starts from it after restart.
+	Commit(ctx context.Context, offset int64) error
+}
+
+// Producer publishes messages.
+type Producer interface {
+	// Produce publishes message to its topic, possibly asynchronously.
+	Produce(ctx context.Context, m Message) error
+	// Flush waits until all produced messages are acknowledged.
+	Flush(ctx context.Context) error
+}
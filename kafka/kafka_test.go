@@ -0,0 +1,130 @@
+package kafka_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/candles/kafka"
+	"github.com/candles/pipelines"
+	"github.com/candles/pipelines/candles"
+)
+
+// mustCandle parses candle from line.
+func mustCandle(t *testing.T, line string) candles.Candle {
+	c, err := candles.CandleFromString(line)
+	assert.NoError(t, err)
+
+	return c
+}
+
+// values returns values of messages.
+func values(msgs []kafka.Message) []string {
+	out := make([]string, 0, len(msgs))
+	for _, m := range msgs {
+		out = append(out, string(m.Value))
+	}
+
+	return out
+}
+
+func TestSource(t *testing.T) {
+	b := kafka.NewBroker()
+	ctx := context.Background()
+
+	assert.NoError(t, b.Produce(ctx, kafka.Message{Topic: "trades", Value: []byte("AAPL,100,10,2019-01-30 10:00:01")}))
+	assert.NoError(t, b.Produce(ctx, kafka.Message{Topic: "trades", Value: []byte("AAPL,101,10,2019-01-30 10:00:02\r\nAAPL,102,10,2019-01-30 10:00:03\n")}))
+	assert.NoError(t, b.Produce(ctx, kafka.Message{Topic: "trades", Value: []byte("AAPL,103,10,2019-01-30 10:00:04")}))
+
+	s := kafka.NewSource(b.Consumer("trades", "candles"), logrus.New())
+	assert.NoError(t, s.SetOffset(1))
+	assert.Equal(t, kafka.ErrInvalidOffset, s.SetOffset(4))
+
+	go s.Init()
+	s.StartChan() <- struct{}{}
+
+	batch := <-s.C()
+	assert.Equal(t, int64(3), batch.Offset)
+	assert.Len(t, batch.Lines, 3)
+	assert.Equal(t, "AAPL,101,10,2019-01-30 10:00:02", string(batch.Lines[0]))
+	assert.Equal(t, "AAPL,103,10,2019-01-30 10:00:04", string(batch.Lines[2]))
+
+	assert.NoError(t, s.Commit(batch.Offset))
+	assert.Equal(t, int64(3), b.Committed("trades", "candles"))
+
+	s.Close()
+
+	_, ok := <-s.C()
+	assert.False(t, ok)
+}
+
+func TestSink(t *testing.T) {
+	b := kafka.NewBroker()
+
+	w, err := kafka.NewSinkBuilder(b, "candles.", logrus.New()).New("candle_5min")
+	assert.NoError(t, err)
+
+	assert.NoError(t, w.Write(pipelines.Batch{
+		Candles: []candles.Candle{
+			mustCandle(t, "AAPL,2019-01-30T10:00:00Z,1.000000,2.000000,0.500000,1.500000"),
+			mustCandle(t, "SBER,2019-01-30T10:00:00Z,3.000000,4.000000,2.000000,3.000000"),
+		},
+		Columns: [][]string{{"final"}, {"partial"}},
+	}))
+
+	size, err := w.(*kafka.Sink).Sync()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), size)
+
+	msgs := b.Messages("candles.candle_5min")
+	assert.Equal(t, []string{
+		"AAPL,2019-01-30T10:00:00Z,1.000000,2.000000,0.500000,1.500000,final",
+		"SBER,2019-01-30T10:00:00Z,3.000000,4.000000,2.000000,3.000000,partial",
+	}, values(msgs))
+	assert.Equal(t, "SBER,2019-01-30T10:00:00Z", string(msgs[1].Key))
+	assert.Equal(t, int64(1), msgs[1].Offset)
+
+	w.Close()
+}
+
+func TestPipelines(t *testing.T) {
+	b := kafka.NewBroker()
+	ctx := context.Background()
+
+	for _, line := range []string{
+		"AAPL,100,10,2019-01-30 10:00:01",
+		"AAPL,110,10,2019-01-30 10:02:01",
+		"AAPL,105,10,2019-01-30 10:05:01",
+	} {
+		assert.NoError(t, b.Produce(ctx, kafka.Message{Topic: "trades", Value: []byte(line)}))
+	}
+
+	l := logrus.New()
+	s := kafka.NewSource(b.Consumer("trades", "candles"), l)
+
+	ps := pipelines.New(s, kafka.NewSinkBuilder(b, "candles.", l), l)
+	ps.EnableCheckpoints(filepath.Join(t.TempDir(), "checkpoint.json"), 0)
+	assert.NoError(t, ps.Add(5))
+
+	go ps.Init()
+	ps.Start <- struct{}{}
+
+	// offsets are committed only after candles before them are published.
+	assert.Eventually(t, func() bool {
+		return b.Committed("trades", "candles") == 3
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"AAPL,2019-01-30T10:00:00Z,100.000000,110.000000,100.000000,110.000000"}, values(b.Messages("candles.candle_5min")))
+
+	s.Close()
+	<-ps.FileDone
+	<-ps.Done
+
+	assert.Equal(t, []string{
+		"AAPL,2019-01-30T10:00:00Z,100.000000,110.000000,100.000000,110.000000",
+		"AAPL,2019-01-30T10:05:00Z,105.000000,105.000000,105.000000,105.000000",
+	}, values(b.Messages("candles.candle_5min")))
+}
@@ -0,0 +1,99 @@
+package kafka
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/candles/pipelines"
+)
+
+// SinkBuilder creates sinks publishing candles of pipelines to per pipeline topics.
+type SinkBuilder struct {
+	p      Producer
+	prefix string
+	l      *logrus.Logger
+}
+
+// NewSinkBuilder creates new SinkBuilder publishing by p
+// to topics named by topicPrefix followed by pipeline name.
+func NewSinkBuilder(p Producer, topicPrefix string, logger *logrus.Logger) SinkBuilder {
+	return SinkBuilder{
+		p:      p,
+		prefix: topicPrefix,
+		l:      logger,
+	}
+}
+
+// New creates new Sink of pipeline output.
+func (sb SinkBuilder) New(name string) (pipelines.Sink, error) {
+	return &Sink{
+		p:     sb.p,
+		topic: sb.prefix + name,
+		l:     sb.l,
+	}, nil
+}
+
+// Open creates new Sink of resumed pipeline output.
+// Published messages can't be discarded, so candles published after the checkpoint
+// are published again with the same keys and are deduplicated by topic compaction.
+func (sb SinkBuilder) Open(name string, _ int64) (pipelines.Sink, error) {
+	return sb.New(name)
+}
+
+// Sink publishes candles lines to topic.
+// Message key is candle ticker and start time, message value is the line.
+type Sink struct {
+	p     Producer
+	topic string
+
+	mu       sync.Mutex
+	produced int64
+
+	l *logrus.Logger
+}
+
+// Write publishes lines of batch candles.
+func (s *Sink) Write(b pipelines.Batch) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, line := range b.Lines() {
+		c := &b.Candles[i]
+
+		err := s.p.Produce(context.Background(), Message{
+			Topic: s.topic,
+			Key:   []byte(c.Ticker() + "," + c.StartTime().Format(time.RFC3339)),
+			Value: []byte(line),
+		})
+		if err != nil {
+			return err
+		}
+
+		s.produced++
+	}
+
+	return nil
+}
+
+// Sync waits for published candles to be acknowledged
+// and returns count of published candles.
+func (s *Sink) Sync() (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.p.Flush(context.Background()); err != nil {
+		return 0, err
+	}
+
+	return s.produced, nil
+}
+
+// Close waits for published candles to be acknowledged.
+func (s *Sink) Close() {
+	if _, err := s.Sync(); err != nil {
+		s.l.Errorf("can't flush %s topic: %v", s.topic, err)
+	}
+}
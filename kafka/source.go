@@ -0,0 +1,137 @@
+package kafka
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/candles/files"
+)
+
+// retryDelay is the delay before fetching again after fetch error.
+const retryDelay = time.Second
+
+// Source consumes trades lines from topic messages and sends them to pipeline in batches.
+// Message value contains one or more lines.
+// Batch offset is the offset of the message following the batch,
+// so consumed offsets are committed by pipelines checkpoints.
+type Source struct {
+	c        Consumer
+	fileData chan files.Batch
+	start    chan struct{}
+	stop     chan struct{}
+	stopOnce sync.Once
+
+	l *logrus.Logger
+}
+
+// NewSource creates new Source consuming messages by c.
+func NewSource(c Consumer, logger *logrus.Logger) *Source {
+	return &Source{
+		c:        c,
+		fileData: make(chan files.Batch),
+		start:    make(chan struct{}),
+		stop:     make(chan struct{}),
+		l:        logger,
+	}
+}
+
+// C returns chan which data would be written to.
+func (s *Source) C() chan files.Batch {
+	return s.fileData
+}
+
+// StartChan returns chan to receive a start signal.
+func (s *Source) StartChan() chan struct{} {
+	return s.start
+}
+
+// SetOffset sets the offset consumption starts from.
+// Must be called before the start signal.
+func (s *Source) SetOffset(offset int64) error {
+	return s.c.SetOffset(offset)
+}
+
+// Commit commits offset of the first not processed message.
+func (s *Source) Commit(offset int64) error {
+	return s.c.Commit(context.Background(), offset)
+}
+
+// Init starts consuming messages after the start signal.
+// Output chan is closed after Close call.
+func (s *Source) Init() {
+	defer close(s.fileData)
+
+	select {
+	case <-s.start:
+	case <-s.stop:
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		<-s.stop
+		cancel()
+	}()
+
+	for {
+		msgs, err := s.c.Fetch(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+
+		if err != nil {
+			s.l.Errorf("can't fetch messages: %v", err)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(retryDelay):
+			}
+
+			continue
+		}
+
+		if len(msgs) == 0 {
+			continue
+		}
+
+		select {
+		case s.fileData <- newBatch(msgs):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Close stops consuming messages.
+func (s *Source) Close() {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+	})
+}
+
+// newBatch creates batch of lines of messages values.
+func newBatch(msgs []Message) files.Batch {
+	b := files.Batch{
+		Lines:  make([][]byte, 0, len(msgs)),
+		Offset: msgs[len(msgs)-1].Offset + 1,
+		Source: msgs[0].Topic,
+	}
+
+	for _, m := range msgs {
+		for _, line := range bytes.Split(m.Value, []byte("\n")) {
+			line = bytes.TrimSuffix(line, []byte("\r"))
+			if len(line) > 0 {
+				b.Lines = append(b.Lines, line)
+			}
+		}
+	}
+
+	return b
+}
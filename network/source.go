@@ -0,0 +1,310 @@
+// Package network receives trades from network producers.
+package network
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/candles/files"
+)
+
+// ErrUnsupportedNetwork is returned for networks other than TCP and UDP.
+var ErrUnsupportedNetwork = errors.New("unsupported network")
+
+const (
+	batchLines = 1024
+	// flushEvery is the max delay of received lines before they are sent to pipeline.
+	flushEvery = 100 * time.Millisecond
+	// maxDatagramSize is the max size of UDP datagram.
+	maxDatagramSize = 64 * 1024
+	// maxLineSize limits memory used by a line of TCP connection,
+	// longer lines are skipped.
+	maxLineSize = 1024 * 1024
+)
+
+// Source receives trades lines from TCP connections or UDP datagrams
+// of any count of producers and sends them to pipeline in batches.
+// Lines of different producers are interleaved in the order they are received.
+type Source struct {
+	ln net.Listener
+	pc net.PacketConn
+
+	lines    chan []byte
+	fileData chan files.Batch
+	start    chan struct{}
+	stop     chan struct{}
+	stopOnce sync.Once
+
+	mu    sync.Mutex
+	conns map[net.Conn]struct{}
+	wg    sync.WaitGroup
+
+	l *logrus.Logger
+}
+
+// Listen creates new Source listening on provided "tcp" or "udp" network address.
+func Listen(network, addr string, logger *logrus.Logger) (*Source, error) {
+	s := &Source{
+		lines:    make(chan []byte, batchLines),
+		fileData: make(chan files.Batch),
+		start:    make(chan struct{}),
+		stop:     make(chan struct{}),
+		conns:    make(map[net.Conn]struct{}),
+		l:        logger,
+	}
+
+	var err error
+
+	switch network {
+	case "tcp", "tcp4", "tcp6":
+		s.ln, err = net.Listen(network, addr)
+	case "udp", "udp4", "udp6":
+		s.pc, err = net.ListenPacket(network, addr)
+	default:
+		return nil, ErrUnsupportedNetwork
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Addr returns address source listens on.
+func (s *Source) Addr() net.Addr {
+	if s.ln != nil {
+		return s.ln.Addr()
+	}
+
+	return s.pc.LocalAddr()
+}
+
+// C returns chan which data would be written to.
+func (s *Source) C() chan files.Batch {
+	return s.fileData
+}
+
+// StartChan returns chan to receive a start signal.
+func (s *Source) StartChan() chan struct{} {
+	return s.start
+}
+
+// Init starts receiving lines after the start signal.
+// Output chan is closed after Close call, when all received lines are sent.
+func (s *Source) Init() {
+	select {
+	case <-s.start:
+	case <-s.stop:
+		s.closeListener()
+		close(s.fileData)
+
+		return
+	}
+
+	s.wg.Add(1)
+
+	if s.ln != nil {
+		go s.accept()
+	} else {
+		go s.receive()
+	}
+
+	go func() {
+		s.wg.Wait()
+		close(s.lines)
+	}()
+
+	s.sendBatches()
+}
+
+// Close stops receiving lines and closes all connections.
+func (s *Source) Close() {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+		s.closeListener()
+
+		s.mu.Lock()
+		for conn := range s.conns {
+			_ = conn.Close()
+		}
+		s.mu.Unlock()
+	})
+}
+
+// closeListener closes listener or packet connection of source.
+func (s *Source) closeListener() {
+	if s.ln != nil {
+		_ = s.ln.Close()
+	} else {
+		_ = s.pc.Close()
+	}
+}
+
+// accept accepts TCP connections until listener is closed.
+func (s *Source) accept() {
+	defer s.wg.Done()
+
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			select {
+			case <-s.stop:
+			default:
+				s.l.Errorf("can't accept connection: %v", err)
+			}
+
+			return
+		}
+
+		s.mu.Lock()
+		select {
+		case <-s.stop:
+			s.mu.Unlock()
+			_ = conn.Close()
+
+			return
+		default:
+		}
+
+		s.conns[conn] = struct{}{}
+		s.wg.Add(1)
+		s.mu.Unlock()
+
+		go s.read(conn)
+	}
+}
+
+// read reads lines of TCP connection until it is closed.
+func (s *Source) read(conn net.Conn) {
+	defer s.wg.Done()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+
+		_ = conn.Close()
+	}()
+
+	var (
+		br = bufio.NewReader(conn)
+		// pending accumulates line longer than read buffer.
+		pending []byte
+		skipped int
+	)
+
+	for {
+		chunk, err := br.ReadSlice('\n')
+
+		// too long line is not kept, so connection isn't closed because of it.
+		if skipped > 0 || len(pending)+len(chunk) > maxLineSize {
+			pending = pending[:0]
+			skipped += len(chunk)
+		} else {
+			pending = append(pending, chunk...)
+		}
+
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+
+		if skipped > 0 {
+			s.l.Errorf("skipped line of %d bytes from %s: line is too long", skipped, conn.RemoteAddr())
+			skipped = 0
+		} else if err == nil || len(pending) > 0 {
+			// the last line may have no line break.
+			line := bytes.TrimSuffix(bytes.TrimSuffix(pending, []byte("\n")), []byte("\r"))
+			s.lines <- append([]byte(nil), line...)
+		}
+
+		pending = pending[:0]
+
+		if err == nil {
+			continue
+		}
+
+		if err != io.EOF {
+			select {
+			case <-s.stop:
+			default:
+				s.l.Errorf("can't read from %s: %v", conn.RemoteAddr(), err)
+			}
+		}
+
+		return
+	}
+}
+
+// receive receives UDP datagrams until packet connection is closed.
+// Each datagram contains one or more complete lines.
+func (s *Source) receive() {
+	defer s.wg.Done()
+
+	buf := make([]byte, maxDatagramSize)
+
+	for {
+		n, _, err := s.pc.ReadFrom(buf)
+		if err != nil {
+			select {
+			case <-s.stop:
+			default:
+				s.l.Errorf("can't receive datagram: %v", err)
+			}
+
+			return
+		}
+
+		for _, line := range bytes.Split(buf[:n], []byte("\n")) {
+			line = bytes.TrimSuffix(line, []byte("\r"))
+			if len(line) > 0 {
+				s.lines <- append([]byte(nil), line...)
+			}
+		}
+	}
+}
+
+// sendBatches collects received lines into batches and sends them to output chan.
+// Not full batch is sent after flushEvery, so lines are not delayed for long.
+func (s *Source) sendBatches() {
+	defer close(s.fileData)
+
+	t := time.NewTicker(flushEvery)
+	defer t.Stop()
+
+	source := s.Addr().Network() + "://" + s.Addr().String()
+	b := files.Batch{Lines: make([][]byte, 0, batchLines), Source: source}
+
+	flush := func() {
+		if len(b.Lines) == 0 {
+			return
+		}
+
+		s.fileData <- b
+		b = files.Batch{Lines: make([][]byte, 0, batchLines), Source: source}
+	}
+
+	for {
+		select {
+		case line, ok := <-s.lines:
+			if !ok {
+				flush()
+				return
+			}
+
+			b.Lines = append(b.Lines, line)
+			if len(b.Lines) == batchLines {
+				flush()
+			}
+		case <-t.C:
+			flush()
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package network_test
+
+import (
+	"net"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/candles/network"
+)
+
+// receive reads lines from source until n lines are received.
+func receive(s *network.Source, n int) []string {
+	got := make([]string, 0, n)
+
+	for b := range s.C() {
+		for _, line := range b.Lines {
+			got = append(got, string(line))
+		}
+
+		if len(got) >= n {
+			break
+		}
+	}
+
+	sort.Strings(got)
+
+	return got
+}
+
+func TestSource_tcp(t *testing.T) {
+	s, err := network.Listen("tcp", "127.0.0.1:0", logrus.New())
+	assert.NoError(t, err)
+
+	go s.Init()
+	s.StartChan() <- struct{}{}
+
+	for _, data := range []string{
+		"AAPL,100,10,2019-01-30 10:00:01\nAAPL,101,10,2019-01-30 10:00:02\n",
+		"MSFT,200,10,2019-01-30 10:00:01\r\nMSFT,201,10,2019-01-30 10:00:02",
+	} {
+		conn, err := net.Dial("tcp", s.Addr().String())
+		assert.NoError(t, err)
+
+		_, err = conn.Write([]byte(data))
+		assert.NoError(t, err)
+		assert.NoError(t, conn.Close())
+	}
+
+	assert.Equal(t, []string{
+		"AAPL,100,10,2019-01-30 10:00:01",
+		"AAPL,101,10,2019-01-30 10:00:02",
+		"MSFT,200,10,2019-01-30 10:00:01",
+		"MSFT,201,10,2019-01-30 10:00:02",
+	}, receive(s, 4))
+
+	s.Close()
+
+	for range s.C() {
+	}
+}
+
+func TestSource_tcpLongLines(t *testing.T) {
+	s, err := network.Listen("tcp", "127.0.0.1:0", logrus.New())
+	assert.NoError(t, err)
+
+	go s.Init()
+	s.StartChan() <- struct{}{}
+
+	conn, err := net.Dial("tcp", s.Addr().String())
+	assert.NoError(t, err)
+
+	long := strings.Repeat("x", 100*1024)
+	tooLong := strings.Repeat("y", 2*1024*1024)
+
+	// lines are received until connection is closed.
+	_, err = conn.Write([]byte(long + "\nAAPL,100,10,2019-01-30 10:00:01\n" + tooLong + "\n"))
+	assert.NoError(t, err)
+
+	_, err = conn.Write([]byte("AAPL,101,10,2019-01-30 10:00:02\n"))
+	assert.NoError(t, err)
+	assert.NoError(t, conn.Close())
+
+	assert.Equal(t, []string{
+		"AAPL,100,10,2019-01-30 10:00:01",
+		"AAPL,101,10,2019-01-30 10:00:02",
+		long,
+	}, receive(s, 3))
+
+	s.Close()
+
+	for range s.C() {
+	}
+}
+
+func TestSource_udp(t *testing.T) {
+	s, err := network.Listen("udp", "127.0.0.1:0", logrus.New())
+	assert.NoError(t, err)
+
+	go s.Init()
+	s.StartChan() <- struct{}{}
+
+	conn, err := net.Dial("udp", s.Addr().String())
+	assert.NoError(t, err)
+
+	_, err = conn.Write([]byte("AAPL,100,10,2019-01-30 10:00:01\nAAPL,101,10,2019-01-30 10:00:02\n"))
+	assert.NoError(t, err)
+	assert.NoError(t, conn.Close())
+
+	assert.Equal(t, []string{
+		"AAPL,100,10,2019-01-30 10:00:01",
+		"AAPL,101,10,2019-01-30 10:00:02",
+	}, receive(s, 2))
+
+	s.Close()
+
+	for range s.C() {
+	}
+}
+
+func TestListen(t *testing.T) {
+	_, err := network.Listen("unix", "/tmp/candles.sock", logrus.New())
+	assert.Equal(t, network.ErrUnsupportedNetwork, err)
+
+	s, err := network.Listen("tcp", "127.0.0.1:0", logrus.New())
+	assert.NoError(t, err)
+
+	// source closed before start doesn't send anything.
+	go s.Init()
+	s.Close()
+
+	_, ok := <-s.C()
+	assert.False(t, ok)
+}
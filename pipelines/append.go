@@ -0,0 +1,197 @@
+package pipelines
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+
+	"github.com/candles/files"
+	"github.com/candles/pipelines/candles"
+)
+
+var errAppendUnsupported = errors.New("pipeline or output doesn't support append")
+
+// appendStateSuffix is the suffix of names of files,
+// which state of the last interval of outputs is saved to.
+const appendStateSuffix = ".state"
+
+// appendState describes candles of the last interval of output.
+// It is saved next to output when pipelines are done,
+// as output lines don't contain trades, volume and turnover of candles.
+type appendState struct {
+	Candles []candles.Candle `json:"candles"`
+}
+
+// Append makes pipelines continue existing outputs instead of recreating them:
+// candles of the last interval in output are merged with new trades
+// and only new candles are appended.
+// Trades before the last interval in output and trades at or before
+// the time of its last trade are skipped, so overlapping inputs aren't counted twice.
+// If the time of the last trade is unknown, as output has no state and trade times,
+// trades of the whole last interval are skipped.
+// Must be called before Add.
+func (ps *Pipelines) Append() {
+	ps.append = true
+}
+
+// pathBuilder is implemented by writers builders of file outputs,
+// which can report path of output file.
+type pathBuilder interface {
+	Path(name string) string
+}
+
+// appendSink opens existing output of worker for append
+// and makes worker continue its last interval.
+// Creates new output if it doesn't exist.
+func (ps *Pipelines) appendSink(w *Worker) (Sink, error) {
+	rb, ok := ps.wb.(resumableBuilder)
+	if !ok || w.bars != nil || w.ha != nil || w.ind != nil || w.partialEvery > 0 {
+		return nil, errAppendUnsupported
+	}
+
+	path := w.name
+	if pb, ok := ps.wb.(pathBuilder); ok {
+		path = pb.Path(w.name)
+	}
+
+	w.appendPath = path
+
+	cs, offset, err := lastIntervalCandles(path)
+	if os.IsNotExist(err) {
+		return ps.wb.New(w.name)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	// state is stale if run was interrupted before output was written.
+	if st, err := loadAppendState(path + appendStateSuffix); err == nil && st.matches(cs) {
+		cs = st.Candles
+	} else if len(cs) > 0 {
+		ps.l.Warnf("No state of the last interval of %s, its trades, volume and turnover are counted from scratch", w.name)
+	}
+
+	if !hasTradeTimes(cs) {
+		ps.l.Warnf("No trade times of the last interval of %s, its trades are skipped not to count them twice", w.name)
+	}
+
+	w.continueFrom(cs)
+	ps.l.Infof("Appending to %s, continuing from %d candles of the last interval", w.name, len(cs))
+
+	return rb.Open(w.name, offset)
+}
+
+// lastIntervalCandles reads candles of the last interval from output file,
+// returns them along with the offset they start at.
+func lastIntervalCandles(path string) ([]candles.Candle, int64, error) {
+	var (
+		cs       []candles.Candle
+		parseErr error
+	)
+
+	lines, offset, err := files.LastLines(path, func(line string) bool {
+		c, err := candles.CandleFromString(line)
+		if err != nil {
+			parseErr = err
+			return false
+		}
+
+		if len(cs) > 0 && !c.StartTime().Equal(cs[0].StartTime()) {
+			return false
+		}
+
+		cs = append(cs, c)
+
+		return true
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// output is corrupted if the last line can't be parsed.
+	if len(lines) == 0 && parseErr != nil {
+		return nil, 0, parseErr
+	}
+
+	// candles were collected from the end of file.
+	for i, j := 0, len(cs)-1; i < j; i, j = i+1, j-1 {
+		cs[i], cs[j] = cs[j], cs[i]
+	}
+
+	return cs, offset, nil
+}
+
+// hasTradeTimes reports whether close trade times of all candles are known.
+func hasTradeTimes(cs []candles.Candle) bool {
+	for i := range cs {
+		if cs[i].CloseTime().IsZero() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// matches reports whether state contains the same candles as output, ignoring not written values.
+func (st appendState) matches(cs []candles.Candle) bool {
+	if len(st.Candles) != len(cs) {
+		return false
+	}
+
+	for i := range cs {
+		if st.Candles[i].String() != cs[i].String() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// saveAppendStates saves state of the last interval of appended outputs.
+// Has to be called after all pipelines are done.
+func (ps *Pipelines) saveAppendStates() {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+
+	for _, w := range ps.workers {
+		if w.appendPath == "" || len(w.lastCandles) == 0 {
+			continue
+		}
+
+		if err := saveAppendState(w.appendPath+appendStateSuffix, appendState{Candles: w.lastCandles}); err != nil {
+			ps.l.Errorf("can't save state of %s: %v", w.name, err)
+		}
+	}
+}
+
+// loadAppendState reads state of output from file.
+func loadAppendState(path string) (appendState, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return appendState{}, err
+	}
+
+	var st appendState
+	if err = json.Unmarshal(data, &st); err != nil {
+		return appendState{}, err
+	}
+
+	return st, nil
+}
+
+// saveAppendState atomically replaces state file.
+func saveAppendState(path string, st appendState) error {
+	data, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err = ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
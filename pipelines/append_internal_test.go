@@ -0,0 +1,201 @@
+package pipelines
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/candles/files"
+	"github.com/candles/pipelines/candles"
+)
+
+func TestAppend_Internal_lastIntervalCandles(t *testing.T) {
+	const (
+		first  = "TICKER_ONE,2019-01-30T11:00:00Z,100.000000,100.000000,100.000000,100.000000\n"
+		second = "TICKER_ONE,2019-01-30T11:05:00Z,200.000000,200.000000,200.000000,200.000000\n"
+		third  = "TICKER_TWO,2019-01-30T11:05:00Z,300.000000,300.000000,300.000000,300.000000\n"
+	)
+
+	dir, err := ioutil.TempDir("", "append")
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		name       string
+		data       string
+		want       []string
+		wantOffset int64
+		wantErr    bool
+	}{
+		{
+			name: "success, multiple candles in last interval",
+			data: first + second + third,
+			want: []string{
+				"TICKER_ONE,2019-01-30T11:05:00Z,200.000000,200.000000,200.000000,200.000000",
+				"TICKER_TWO,2019-01-30T11:05:00Z,300.000000,300.000000,300.000000,300.000000",
+			},
+			wantOffset: int64(len(first)),
+		},
+		{
+			name:       "success, single interval",
+			data:       first,
+			want:       []string{"TICKER_ONE,2019-01-30T11:00:00Z,100.000000,100.000000,100.000000,100.000000"},
+			wantOffset: 0,
+		},
+		{
+			name:       "success, empty output",
+			data:       "",
+			want:       []string{},
+			wantOffset: 0,
+		},
+		{
+			name:    "corrupted output",
+			data:    first + "TICKER_ONE,2019-01-30",
+			wantErr: true,
+		},
+	}
+
+	for i, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(dir, string(rune('a'+i)))
+			assert.NoError(t, ioutil.WriteFile(path, []byte(test.data), 0600))
+
+			cs, offset, err := lastIntervalCandles(path)
+			assert.Equal(t, test.wantErr, err != nil)
+
+			if test.wantErr {
+				return
+			}
+
+			got := make([]string, 0, len(cs))
+			for _, c := range cs {
+				got = append(got, c.String())
+			}
+
+			assert.Equal(t, test.want, got)
+			assert.Equal(t, test.wantOffset, offset)
+		})
+	}
+}
+
+func TestWorker_Internal_continueFrom(t *testing.T) {
+	iStart := mustParseTime("2019-01-30 11:05:00.000000")
+
+	w := NewWorker(5)
+	w.continueFrom([]candles.Candle{
+		*candles.New(candles.MustTradeFromString("TICKER,200.000000,10,2019-01-30 11:06:00.000000"), iStart),
+	})
+
+	assert.Equal(t, iStart, w.intervalStart)
+	assert.Equal(t, iStart.Add(time.Minute*5), w.intervalEnd)
+
+	go w.start()
+
+	for _, s := range []string{
+		"TICKER,100.000000,10,2019-01-30 11:04:00.000000",
+		"TICKER,300.000000,10,2019-01-30 11:07:00.000000",
+		"TICKER,250.000000,10,2019-01-30 11:08:00.000000",
+	} {
+		w.in <- candles.MustTradeFromString(s)
+	}
+
+	close(w.in)
+
+	assert.Equal(t, "TICKER,2019-01-30T11:05:00Z,200.000000,300.000000,200.000000,250.000000", (<-w.out).String())
+}
+
+// runAppend runs 5 minutes pipeline appending candles of trades lines to output in dir.
+func runAppend(t *testing.T, dir string, lines ...string) {
+	r := readerMock{c: make(chan files.Batch)}
+
+	ps := New(r, WriterBuilder{Dir: dir}, logrus.New())
+	ps.Append()
+	assert.NoError(t, ps.Add(5))
+
+	go ps.Init()
+
+	b := files.Batch{}
+	for _, l := range lines {
+		b.Lines = append(b.Lines, []byte(l))
+	}
+
+	r.c <- b
+	close(r.c)
+
+	<-ps.FileDone
+	<-ps.Done
+}
+
+func TestPipelines_Internal_appendOverlapping(t *testing.T) {
+	dir, err := ioutil.TempDir("", "append")
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	const (
+		first   = "TICKER,100,10,2019-01-30 11:01:00"
+		second  = "TICKER,200,10,2019-01-30 11:05:30"
+		third   = "TICKER,210,10,2019-01-30 11:06:00"
+		fourth  = "TICKER,190,10,2019-01-30 11:07:00"
+		wantOut = "TICKER,2019-01-30T11:00:00Z,100.000000,100.000000,100.000000,100.000000\n" +
+			"TICKER,2019-01-30T11:05:00Z,200.000000,210.000000,190.000000,190.000000\n"
+	)
+
+	runAppend(t, dir, first, second, third)
+	// trades at or before the last trade in output are present in it already.
+	runAppend(t, dir, second, third, fourth)
+
+	out, err := ioutil.ReadFile(filepath.Join(dir, "candle_5min"))
+	assert.NoError(t, err)
+	assert.Equal(t, wantOut, string(out))
+
+	st, err := loadAppendState(filepath.Join(dir, "candle_5min"+appendStateSuffix))
+	assert.NoError(t, err)
+	assert.Len(t, st.Candles, 1)
+
+	c := st.Candles[0]
+	assert.Equal(t, 3, c.Trades())
+	assert.Equal(t, 30, c.Volume())
+	assert.Equal(t, 6000.0, c.Turnover())
+
+	// state equals to state of uninterrupted run.
+	single := filepath.Join(dir, "single")
+	assert.NoError(t, os.Mkdir(single, 0700))
+
+	runAppend(t, single, first, second, third, fourth)
+
+	want, err := loadAppendState(filepath.Join(single, "candle_5min"+appendStateSuffix))
+	assert.NoError(t, err)
+	assert.True(t, want.Candles[0].Equal(&c))
+}
+
+func TestPipelines_Internal_appendWithoutTradeTimes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "append")
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	const out = "TICKER,2019-01-30T11:00:00Z,100.000000,100.000000,100.000000,100.000000\n" +
+		"TICKER,2019-01-30T11:05:00Z,200.000000,210.000000,200.000000,210.000000\n"
+
+	path := filepath.Join(dir, "candle_5min")
+	assert.NoError(t, ioutil.WriteFile(path, []byte(out), 0600))
+
+	// trades of the last interval may be present in output already, so they are skipped.
+	runAppend(t, dir,
+		"TICKER,200,10,2019-01-30 11:05:30",
+		"TICKER,210,10,2019-01-30 11:06:00",
+		"TICKER,190,10,2019-01-30 11:07:00",
+		"TICKER,220,10,2019-01-30 11:10:00",
+	)
+
+	got, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, out+"TICKER,2019-01-30T11:10:00Z,220.000000,220.000000,220.000000,220.000000\n", string(got))
+}
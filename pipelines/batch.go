@@ -0,0 +1,78 @@
+package pipelines
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/candles/pipelines/candles"
+)
+
+// Batch contains candles sent by pipeline to its output at once.
+type Batch struct {
+	// Output is the name of pipeline output, e.g. candle_5min.
+	Output string
+	// Interval is the pipeline interval in minutes,
+	// zero for bars closed by trading activity, which are described by Output.
+	Interval int
+	// Partial reports whether candles are not closed yet.
+	Partial bool
+	Candles []candles.Candle
+	// Columns contains additional output values of each candle,
+	// e.g. order flow, indicators or status, in output order.
+	Columns [][]string
+}
+
+// Lines returns CSV lines of candles followed by their columns.
+func (b Batch) Lines() []string {
+	lines := make([]string, 0, len(b.Candles))
+
+	for i := range b.Candles {
+		line := b.Candles[i].String()
+		if i < len(b.Columns) && len(b.Columns[i]) > 0 {
+			line += "," + strings.Join(b.Columns[i], ",")
+		}
+
+		lines = append(lines, line)
+	}
+
+	return lines
+}
+
+// String returns CSV lines of candles joined into a single chunk.
+func (b Batch) String() string {
+	return strings.Join(b.Lines(), "\n")
+}
+
+// jsonRecord is a JSON representation of batch candle.
+type jsonRecord struct {
+	Output  string          `json:"output"`
+	Partial bool            `json:"partial,omitempty"`
+	Candle  *candles.Candle `json:"candle"`
+	Columns []string        `json:"columns,omitempty"`
+}
+
+// JSON returns JSON lines of candles with their columns.
+func (b Batch) JSON() (string, error) {
+	lines := make([]string, 0, len(b.Candles))
+
+	for i := range b.Candles {
+		r := jsonRecord{
+			Output:  b.Output,
+			Partial: b.Partial,
+			Candle:  &b.Candles[i],
+		}
+
+		if i < len(b.Columns) {
+			r.Columns = b.Columns[i]
+		}
+
+		data, err := json.Marshal(r)
+		if err != nil {
+			return "", err
+		}
+
+		lines = append(lines, string(data))
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
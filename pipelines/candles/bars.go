@@ -0,0 +1,311 @@
+package candles
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"time"
+)
+
+// ErrInvalidBarKind is returned for unknown bar kinds.
+var ErrInvalidBarKind = errors.New("invalid bar kind")
+
+// BarKind describes rule bars are closed by.
+type BarKind int
+
+// BarKind values.
+const (
+	// TickBars are closed every N trades.
+	TickBars BarKind = iota + 1
+	// VolumeBars are closed every N traded units.
+	VolumeBars
+	// DollarBars are closed every N currency units of turnover.
+	DollarBars
+	// RangeBars are closed when difference between high and low prices
+	// would exceed N.
+	RangeBars
+	// RenkoBricks are built every time price moves by N from the last brick.
+	RenkoBricks
+)
+
+// ParseBarKind parses BarKind from its string value.
+func ParseBarKind(s string) (BarKind, error) {
+	for _, k := range []BarKind{TickBars, VolumeBars, DollarBars, RangeBars, RenkoBricks} {
+		if k.String() == s {
+			return k, nil
+		}
+	}
+
+	return 0, ErrInvalidBarKind
+}
+
+// String returns string value of BarKind.
+func (k BarKind) String() string {
+	switch k {
+	case TickBars:
+		return "tick"
+	case VolumeBars:
+		return "volume"
+	case DollarBars:
+		return "dollar"
+	case RangeBars:
+		return "range"
+	case RenkoBricks:
+		return "renko"
+	default:
+		return ""
+	}
+}
+
+// PriceDriven reports whether bars of the kind are closed by price moves.
+func (k BarKind) PriceDriven() bool {
+	return k == RangeBars || k == RenkoBricks
+}
+
+// Bars builds bars closed by trading activity or price moves separately for each ticker.
+// Bar start time is the time of its first trade,
+// bar end time is the time of its last trade.
+type Bars struct {
+	kind   BarKind
+	size   float64
+	sizes  map[ticker]float64
+	data   map[ticker]*Candle
+	bricks map[ticker]*brick
+}
+
+// brick describes the last Renko brick of a ticker.
+type brick struct {
+	Top    float64   `json:"top"`
+	Bottom float64   `json:"bottom"`
+	Start  time.Time `json:"start"`
+}
+
+// NewBars creates new Bars of provided kind,
+// which are closed when their measure reaches size.
+func NewBars(kind BarKind, size float64) *Bars {
+	return &Bars{
+		kind:   kind,
+		size:   size,
+		sizes:  make(map[ticker]float64),
+		data:   make(map[ticker]*Candle),
+		bricks: make(map[ticker]*brick),
+	}
+}
+
+// SetSize sets bars size for provided ticker.
+func (b *Bars) SetSize(t string, size float64) {
+	b.sizes[ticker(t)] = size
+}
+
+// AddTrade adds trade to bar of its ticker.
+// Returns bars closed by the trade.
+func (b *Bars) AddTrade(trade Trade) []Candle {
+	switch b.kind {
+	case RangeBars:
+		return b.addRangeTrade(trade)
+	case RenkoBricks:
+		return b.addRenkoTrade(trade)
+	case TickBars, VolumeBars, DollarBars:
+		return b.addActivityTrade(trade)
+	default:
+		return nil
+	}
+}
+
+// Candles returns not closed bars ordered by ticker.
+func (b *Bars) Candles() []Candle {
+	out := make([]Candle, 0, len(b.data))
+	for _, c := range b.data {
+		out = append(out, *c)
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].t < out[j].t
+	})
+
+	return out
+}
+
+// barsJSON is a JSON representation of Bars state.
+type barsJSON struct {
+	Candles []Candle          `json:"candles"`
+	Bricks  map[string]*brick `json:"bricks,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler.
+// Only state of bars is marshaled, kind and sizes are not.
+func (b *Bars) MarshalJSON() ([]byte, error) {
+	v := barsJSON{
+		Candles: b.Candles(),
+		Bricks:  make(map[string]*brick, len(b.bricks)),
+	}
+
+	for t, br := range b.bricks {
+		v.Bricks[string(t)] = br
+	}
+
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+// Kind and sizes of bars are kept.
+func (b *Bars) UnmarshalJSON(data []byte) error {
+	var v barsJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	b.data = make(map[ticker]*Candle, len(v.Candles))
+	for i := range v.Candles {
+		b.data[v.Candles[i].t] = &v.Candles[i]
+	}
+
+	b.bricks = make(map[ticker]*brick, len(v.Bricks))
+	for t, br := range v.Bricks {
+		b.bricks[ticker(t)] = br
+	}
+
+	return nil
+}
+
+// sizeOf returns bars size of ticker.
+func (b *Bars) sizeOf(t ticker) float64 {
+	if size, ok := b.sizes[t]; ok {
+		return size
+	}
+
+	return b.size
+}
+
+// add adds trade to bar of its ticker, creating the bar if needed.
+func (b *Bars) add(trade Trade) *Candle {
+	c, ok := b.data[trade.t]
+	if !ok {
+		c = New(trade, trade.Timestamp)
+		b.data[trade.t] = c
+	} else {
+		c.AddTrade(trade)
+	}
+
+	c.endTime = trade.Timestamp
+
+	return c
+}
+
+// addActivityTrade adds trade to tick, volume or dollar bar,
+// closing the bar when its measure reaches size.
+func (b *Bars) addActivityTrade(trade Trade) []Candle {
+	c := b.add(trade)
+
+	var measure float64
+
+	switch b.kind {
+	case TickBars:
+		measure = float64(c.trades)
+	case VolumeBars:
+		measure = float64(c.volume)
+	default:
+		measure = c.turnover
+	}
+
+	if measure < b.sizeOf(trade.t) {
+		return nil
+	}
+
+	delete(b.data, trade.t)
+
+	return []Candle{*c}
+}
+
+// addRangeTrade adds trade to range bar.
+// If the trade would make the bar range exceed size,
+// the bar is closed and a new one is started with the trade.
+func (b *Bars) addRangeTrade(trade Trade) []Candle {
+	c, ok := b.data[trade.t]
+	if !ok {
+		b.add(trade)
+		return nil
+	}
+
+	high, low := c.maxPrice, c.minPrice
+	if trade.price > high {
+		high = trade.price
+	}
+
+	if trade.price < low {
+		low = trade.price
+	}
+
+	if high-low <= b.sizeOf(trade.t) {
+		b.add(trade)
+		return nil
+	}
+
+	closed := *c
+
+	delete(b.data, trade.t)
+	b.add(trade)
+
+	return []Candle{closed}
+}
+
+// addRenkoTrade moves Renko bricks of trade ticker.
+// A new brick is built when price moves by size above the last brick top
+// or below the last brick bottom, so reversal needs two bricks move.
+func (b *Bars) addRenkoTrade(trade Trade) []Candle {
+	br, ok := b.bricks[trade.t]
+	if !ok {
+		b.bricks[trade.t] = &brick{
+			Top:    trade.price,
+			Bottom: trade.price,
+			Start:  trade.Timestamp,
+		}
+
+		return nil
+	}
+
+	size := b.sizeOf(trade.t)
+	if size <= 0 {
+		return nil
+	}
+
+	var out []Candle
+
+	for trade.price >= br.Top+size {
+		out = append(out, b.newBrick(trade, br, br.Top, br.Top+size))
+		br.Bottom, br.Top = br.Top, br.Top+size
+	}
+
+	for trade.price <= br.Bottom-size {
+		out = append(out, b.newBrick(trade, br, br.Bottom, br.Bottom-size))
+		br.Top, br.Bottom = br.Bottom, br.Bottom-size
+	}
+
+	return out
+}
+
+// newBrick creates Renko brick completed by trade.
+func (b *Bars) newBrick(trade Trade, br *brick, open, close float64) Candle {
+	c := Candle{
+		t:          trade.t,
+		startTime:  br.Start,
+		endTime:    trade.Timestamp,
+		openPrice:  open,
+		maxPrice:   open,
+		minPrice:   close,
+		closePrice: close,
+		openTime:   br.Start,
+		highTime:   br.Start,
+		lowTime:    trade.Timestamp,
+		closeTime:  trade.Timestamp,
+	}
+
+	if close > open {
+		c.maxPrice, c.minPrice = close, open
+		c.highTime, c.lowTime = trade.Timestamp, br.Start
+	}
+
+	br.Start = trade.Timestamp
+
+	return c
+}
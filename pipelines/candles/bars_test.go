@@ -0,0 +1,197 @@
+package candles_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/candles/pipelines/candles"
+)
+
+func TestBars_AddTrade(t *testing.T) {
+	type args struct {
+		kind   candles.BarKind
+		size   float64
+		sizes  map[string]float64
+		trades []string
+	}
+
+	tests := []struct {
+		name       string
+		args       args
+		wantClosed []string
+		wantOpen   []string
+	}{
+		{
+			name: "tick bars",
+			args: args{
+				kind: candles.TickBars,
+				size: 2,
+				trades: []string{
+					"TICKER_ONE,100,10,2019-01-30 11:00:01",
+					"TICKER_TWO,300,10,2019-01-30 11:00:02",
+					"TICKER_ONE,200,10,2019-01-30 11:00:03",
+					"TICKER_ONE,150,10,2019-01-30 11:00:04",
+				},
+			},
+			wantClosed: []string{
+				"TICKER_ONE,2019-01-30T11:00:01Z,100.000000,200.000000,100.000000,200.000000," +
+					"2019-01-30T11:00:01Z,2019-01-30T11:00:03Z",
+			},
+			wantOpen: []string{
+				"TICKER_ONE,2019-01-30T11:00:04Z,150.000000,150.000000,150.000000,150.000000",
+				"TICKER_TWO,2019-01-30T11:00:02Z,300.000000,300.000000,300.000000,300.000000",
+			},
+		},
+		{
+			name: "volume bars",
+			args: args{
+				kind: candles.VolumeBars,
+				size: 25,
+				trades: []string{
+					"TICKER,100,10,2019-01-30 11:00:01",
+					"TICKER,200,10,2019-01-30 11:00:02",
+					"TICKER,50,10,2019-01-30 11:00:03",
+					"TICKER,150,30,2019-01-30 11:00:04",
+				},
+			},
+			wantClosed: []string{
+				"TICKER,2019-01-30T11:00:01Z,100.000000,200.000000,50.000000,50.000000," +
+					"2019-01-30T11:00:01Z,2019-01-30T11:00:03Z",
+				"TICKER,2019-01-30T11:00:04Z,150.000000,150.000000,150.000000,150.000000," +
+					"2019-01-30T11:00:04Z,2019-01-30T11:00:04Z",
+			},
+			wantOpen: []string{},
+		},
+		{
+			name: "dollar bars",
+			args: args{
+				kind: candles.DollarBars,
+				size: 3000,
+				trades: []string{
+					"TICKER,100,10,2019-01-30 11:00:01",
+					"TICKER,200,10,2019-01-30 11:00:02",
+					"TICKER,50,10,2019-01-30 11:00:03",
+				},
+			},
+			wantClosed: []string{
+				"TICKER,2019-01-30T11:00:01Z,100.000000,200.000000,100.000000,200.000000," +
+					"2019-01-30T11:00:01Z,2019-01-30T11:00:02Z",
+			},
+			wantOpen: []string{
+				"TICKER,2019-01-30T11:00:03Z,50.000000,50.000000,50.000000,50.000000",
+			},
+		},
+		{
+			name: "range bars",
+			args: args{
+				kind:  candles.RangeBars,
+				size:  1,
+				sizes: map[string]float64{"TICKER_TWO": 10},
+				trades: []string{
+					"TICKER_ONE,100,10,2019-01-30 11:00:01",
+					"TICKER_ONE,100.5,10,2019-01-30 11:00:02.5",
+					"TICKER_ONE,99.5,10,2019-01-30 11:00:03",
+					"TICKER_ONE,99.4,10,2019-01-30 11:00:04",
+					"TICKER_TWO,100,10,2019-01-30 11:00:05",
+					"TICKER_TWO,105,10,2019-01-30 11:00:06",
+				},
+			},
+			wantClosed: []string{
+				"TICKER_ONE,2019-01-30T11:00:01Z,100.000000,100.500000,99.500000,99.500000," +
+					"2019-01-30T11:00:01Z,2019-01-30T11:00:03Z",
+			},
+			wantOpen: []string{
+				"TICKER_ONE,2019-01-30T11:00:04Z,99.400000,99.400000,99.400000,99.400000",
+				"TICKER_TWO,2019-01-30T11:00:05Z,100.000000,105.000000,100.000000,105.000000",
+			},
+		},
+		{
+			name: "renko bricks",
+			args: args{
+				kind: candles.RenkoBricks,
+				size: 1,
+				trades: []string{
+					"TICKER,100,10,2019-01-30 11:00:01",
+					"TICKER,100.5,10,2019-01-30 11:00:02",
+					"TICKER,102.2,10,2019-01-30 11:00:03.5",
+					"TICKER,100.5,10,2019-01-30 11:00:04",
+					"TICKER,99.9,10,2019-01-30 11:00:05",
+				},
+			},
+			wantClosed: []string{
+				"TICKER,2019-01-30T11:00:01Z,100.000000,101.000000,100.000000,101.000000," +
+					"2019-01-30T11:00:01Z,2019-01-30T11:00:03.5Z",
+				"TICKER,2019-01-30T11:00:03Z,101.000000,102.000000,101.000000,102.000000," +
+					"2019-01-30T11:00:03.5Z,2019-01-30T11:00:03.5Z",
+				"TICKER,2019-01-30T11:00:03Z,101.000000,101.000000,100.000000,100.000000," +
+					"2019-01-30T11:00:03.5Z,2019-01-30T11:00:05Z",
+			},
+			wantOpen: []string{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b := candles.NewBars(test.args.kind, test.args.size)
+
+			for tk, size := range test.args.sizes {
+				b.SetSize(tk, size)
+			}
+
+			closed := make([]string, 0, len(test.wantClosed))
+			for _, s := range test.args.trades {
+				for _, c := range b.AddTrade(candles.MustTradeFromString(s)) {
+					closed = append(closed, c.String()+","+c.TimesString())
+				}
+			}
+
+			open := make([]string, 0, len(test.wantOpen))
+			for _, c := range b.Candles() {
+				open = append(open, c.String())
+			}
+
+			assert.Equal(t, test.wantClosed, closed)
+			assert.Equal(t, test.wantOpen, open)
+		})
+	}
+}
+
+func TestParseBarKind(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    candles.BarKind
+		wantErr error
+	}{
+		{s: "tick", want: candles.TickBars},
+		{s: "volume", want: candles.VolumeBars},
+		{s: "dollar", want: candles.DollarBars},
+		{s: "time", wantErr: candles.ErrInvalidBarKind},
+	}
+
+	for _, test := range tests {
+		t.Run(test.s, func(t *testing.T) {
+			got, err := candles.ParseBarKind(test.s)
+			assert.Equal(t, test.want, got)
+			assert.Equal(t, test.wantErr, err)
+		})
+	}
+}
+
+func TestBars_JSON(t *testing.T) {
+	b := candles.NewBars(candles.RenkoBricks, 1)
+	b.AddTrade(candles.MustTradeFromString("TICKER,100,10,2019-01-30 11:00:01"))
+	b.AddTrade(candles.MustTradeFromString("TICKER,101.5,10,2019-01-30 11:00:02"))
+
+	data, err := json.Marshal(b)
+	assert.NoError(t, err)
+
+	restored := candles.NewBars(candles.RenkoBricks, 1)
+	assert.NoError(t, json.Unmarshal(data, restored))
+
+	trade := candles.MustTradeFromString("TICKER,98.9,10,2019-01-30 11:00:03")
+	want := b.AddTrade(trade)
+	assert.Len(t, want, 1)
+	assert.Equal(t, want, restored.AddTrade(trade))
+}
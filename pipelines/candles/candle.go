@@ -1,43 +1,279 @@
 package candles
 
 import (
+	"encoding/json"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 )
 
+const (
+	candleDataLen    = 6
+	orderFlowDataLen = 3
+	tradeTimesLen    = 4
+)
+
 // Candle contains data about current interval deals.
+// Candle values are available by getters,
+// candles are created from trades by New or parsed by CandleFromString.
 type Candle struct {
 	t          ticker
 	startTime  time.Time
+	endTime    time.Time
 	openPrice  float64
 	maxPrice   float64
 	minPrice   float64
 	closePrice float64
+	buyVolume  int
+	sellVolume int
+	trades     int
+	volume     int
+	turnover   float64
+	// times of trades candle prices were set by.
+	openTime  time.Time
+	highTime  time.Time
+	lowTime   time.Time
+	closeTime time.Time
 }
 
 // Candle creates new Candle from initial Trade.
 func New(trade Trade, iStart time.Time) *Candle {
-	return &Candle{
+	c := &Candle{
 		t:          trade.t,
 		startTime:  iStart,
 		openPrice:  trade.price,
 		maxPrice:   trade.price,
 		minPrice:   trade.price,
 		closePrice: trade.price,
+		openTime:   trade.Timestamp,
+		highTime:   trade.Timestamp,
+		lowTime:    trade.Timestamp,
+		closeTime:  trade.Timestamp,
+	}
+	c.addVolume(trade)
+
+	return c
+}
+
+// CandleFromString parses Candle from string produced by Candle.String,
+// optionally followed by Candle.OrderFlowString values
+// and then by Candle.TradeTimesString values.
+func CandleFromString(s string) (Candle, error) {
+	values := strings.Split(strings.TrimSpace(s), ",")
+
+	var withOrderFlow, withTradeTimes bool
+
+	switch len(values) {
+	case candleDataLen:
+	case candleDataLen + orderFlowDataLen:
+		withOrderFlow = true
+	case candleDataLen + tradeTimesLen:
+		withTradeTimes = true
+	case candleDataLen + orderFlowDataLen + tradeTimesLen:
+		withOrderFlow, withTradeTimes = true, true
+	default:
+		return Candle{}, ErrInvalidValue
+	}
+
+	if len(values[0]) == 0 {
+		return Candle{}, ErrInvalidTicker
+	}
+
+	startTime, err := time.Parse(time.RFC3339, values[1])
+	if err != nil {
+		return Candle{}, ErrInvalidTime
+	}
+
+	prices := make([]float64, 0, candleDataLen-2)
+
+	for _, v := range values[2:candleDataLen] {
+		price, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return Candle{}, ErrInvalidPrice
+		}
+
+		prices = append(prices, price)
+	}
+
+	c := Candle{
+		t:          ticker(values[0]),
+		startTime:  startTime,
+		openPrice:  prices[0],
+		maxPrice:   prices[1],
+		minPrice:   prices[2],
+		closePrice: prices[3],
+	}
+
+	values = values[candleDataLen:]
+
+	if withOrderFlow {
+		if c.buyVolume, err = strconv.Atoi(values[0]); err != nil {
+			return Candle{}, ErrInvalidCount
+		}
+
+		if c.sellVolume, err = strconv.Atoi(values[1]); err != nil {
+			return Candle{}, ErrInvalidCount
+		}
+
+		values = values[orderFlowDataLen:]
+	}
+
+	if withTradeTimes {
+		times := make([]time.Time, 0, tradeTimesLen)
+
+		for _, v := range values {
+			t, err := time.Parse(time.RFC3339Nano, v)
+			if err != nil {
+				return Candle{}, ErrInvalidTime
+			}
+
+			times = append(times, t)
+		}
+
+		c.openTime, c.highTime, c.lowTime, c.closeTime = times[0], times[1], times[2], times[3]
 	}
+
+	return c, nil
+}
+
+// Ticker returns ticker of Candle.
+func (c *Candle) Ticker() string {
+	return string(c.t)
+}
+
+// StartTime returns start time of Candle interval.
+func (c *Candle) StartTime() time.Time {
+	return c.startTime
+}
+
+// OpenPrice returns price of the first trade of Candle.
+func (c *Candle) OpenPrice() float64 {
+	return c.openPrice
+}
+
+// HighPrice returns max price of Candle trades.
+func (c *Candle) HighPrice() float64 {
+	return c.maxPrice
+}
+
+// LowPrice returns min price of Candle trades.
+func (c *Candle) LowPrice() float64 {
+	return c.minPrice
+}
+
+// ClosePrice returns price of the last trade of Candle.
+func (c *Candle) ClosePrice() float64 {
+	return c.closePrice
 }
 
 // AddTrade adds Trade to Candle.
+// High and low times are times of the first trades the prices were reached by.
 func (c *Candle) AddTrade(trade Trade) {
 	if trade.price > c.maxPrice {
 		c.maxPrice = trade.price
+		c.highTime = trade.Timestamp
 	}
 
 	if trade.price < c.minPrice {
 		c.minPrice = trade.price
+		c.lowTime = trade.Timestamp
 	}
 
 	c.closePrice = trade.price
+	c.closeTime = trade.Timestamp
+	c.addVolume(trade)
+}
+
+// addVolume adds Trade to volume values.
+func (c *Candle) addVolume(trade Trade) {
+	c.trades++
+	c.volume += trade.count
+	c.turnover += trade.price * float64(trade.count)
+
+	switch trade.side {
+	case SideBuy:
+		c.buyVolume += trade.count
+	case SideSell:
+		c.sellVolume += trade.count
+	case SideUnknown:
+	}
+}
+
+// EndTime returns time of the last trade of bar.
+// It is zero for candles of time intervals.
+func (c *Candle) EndTime() time.Time {
+	return c.endTime
+}
+
+// TimesString returns string values of bar start and end times with nanoseconds.
+func (c *Candle) TimesString() string {
+	return c.startTime.Format(time.RFC3339Nano) + "," + c.endTime.Format(time.RFC3339Nano)
+}
+
+// OpenTime returns time of the open trade.
+func (c *Candle) OpenTime() time.Time {
+	return c.openTime
+}
+
+// HighTime returns time of the trade high price was reached by.
+func (c *Candle) HighTime() time.Time {
+	return c.highTime
+}
+
+// LowTime returns time of the trade low price was reached by.
+func (c *Candle) LowTime() time.Time {
+	return c.lowTime
+}
+
+// CloseTime returns time of the close trade.
+func (c *Candle) CloseTime() time.Time {
+	return c.closeTime
+}
+
+// TradeTimesString returns string values of open, high, low and close trades times with nanoseconds.
+func (c *Candle) TradeTimesString() string {
+	return strings.Join([]string{
+		c.openTime.Format(time.RFC3339Nano),
+		c.highTime.Format(time.RFC3339Nano),
+		c.lowTime.Format(time.RFC3339Nano),
+		c.closeTime.Format(time.RFC3339Nano),
+	}, ",")
+}
+
+// Trades returns count of trades in Candle.
+func (c *Candle) Trades() int {
+	return c.trades
+}
+
+// Volume returns total count of traded units.
+func (c *Candle) Volume() int {
+	return c.volume
+}
+
+// Turnover returns total traded value.
+func (c *Candle) Turnover() float64 {
+	return c.turnover
+}
+
+// BuyVolume returns volume of buy side trades.
+func (c *Candle) BuyVolume() int {
+	return c.buyVolume
+}
+
+// SellVolume returns volume of sell side trades.
+func (c *Candle) SellVolume() int {
+	return c.sellVolume
+}
+
+// Delta returns difference between buy and sell volumes.
+func (c *Candle) Delta() int {
+	return c.buyVolume - c.sellVolume
+}
+
+// OrderFlowString returns string values of Candle buy volume, sell volume and delta.
+func (c *Candle) OrderFlowString() string {
+	return fmt.Sprintf("%d,%d,%d", c.buyVolume, c.sellVolume, c.Delta())
 }
 
 // String returns string values of Candle.
@@ -51,3 +287,127 @@ func (c *Candle) String() string {
 		c.closePrice,
 	)
 }
+
+// Equal reports whether candles have equal values.
+// Times are compared by time.Time.Equal.
+func (c *Candle) Equal(o *Candle) bool {
+	return c.t == o.t &&
+		c.startTime.Equal(o.startTime) &&
+		c.endTime.Equal(o.endTime) &&
+		c.openPrice == o.openPrice &&
+		c.maxPrice == o.maxPrice &&
+		c.minPrice == o.minPrice &&
+		c.closePrice == o.closePrice &&
+		c.buyVolume == o.buyVolume &&
+		c.sellVolume == o.sellVolume &&
+		c.trades == o.trades &&
+		c.volume == o.volume &&
+		c.turnover == o.turnover &&
+		c.openTime.Equal(o.openTime) &&
+		c.highTime.Equal(o.highTime) &&
+		c.lowTime.Equal(o.lowTime) &&
+		c.closeTime.Equal(o.closeTime)
+}
+
+// CompareCandles orders candles by start time and then by ticker.
+// Returns -1 if a is before b, 1 if a is after b and 0 otherwise.
+func CompareCandles(a, b *Candle) int {
+	if c := compareTimes(a.startTime, b.startTime); c != 0 {
+		return c
+	}
+
+	return strings.Compare(string(a.t), string(b.t))
+}
+
+// candleJSON is a JSON representation of Candle.
+type candleJSON struct {
+	Ticker     string     `json:"ticker"`
+	StartTime  time.Time  `json:"start_time"`
+	EndTime    *time.Time `json:"end_time,omitempty"`
+	OpenPrice  float64    `json:"open"`
+	MaxPrice   float64    `json:"high"`
+	MinPrice   float64    `json:"low"`
+	ClosePrice float64    `json:"close"`
+	BuyVolume  int        `json:"buy_volume,omitempty"`
+	SellVolume int        `json:"sell_volume,omitempty"`
+	Trades     int        `json:"trades,omitempty"`
+	Volume     int        `json:"volume,omitempty"`
+	Turnover   float64    `json:"turnover,omitempty"`
+	OpenTime   *time.Time `json:"open_time,omitempty"`
+	HighTime   *time.Time `json:"high_time,omitempty"`
+	LowTime    *time.Time `json:"low_time,omitempty"`
+	CloseTime  *time.Time `json:"close_time,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler.
+// Like other methods of Candle it has pointer receiver,
+// so candles have to be marshaled by pointers or as elements of slices.
+func (c *Candle) MarshalJSON() ([]byte, error) {
+	return json.Marshal(candleJSON{
+		Ticker:     string(c.t),
+		StartTime:  c.startTime,
+		EndTime:    timeOrNil(c.endTime),
+		OpenPrice:  c.openPrice,
+		MaxPrice:   c.maxPrice,
+		MinPrice:   c.minPrice,
+		ClosePrice: c.closePrice,
+		BuyVolume:  c.buyVolume,
+		SellVolume: c.sellVolume,
+		Trades:     c.trades,
+		Volume:     c.volume,
+		Turnover:   c.turnover,
+		OpenTime:   timeOrNil(c.openTime),
+		HighTime:   timeOrNil(c.highTime),
+		LowTime:    timeOrNil(c.lowTime),
+		CloseTime:  timeOrNil(c.closeTime),
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (c *Candle) UnmarshalJSON(data []byte) error {
+	var v candleJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	*c = Candle{
+		t:          ticker(v.Ticker),
+		startTime:  v.StartTime,
+		openPrice:  v.OpenPrice,
+		maxPrice:   v.MaxPrice,
+		minPrice:   v.MinPrice,
+		closePrice: v.ClosePrice,
+		buyVolume:  v.BuyVolume,
+		sellVolume: v.SellVolume,
+		trades:     v.Trades,
+		volume:     v.Volume,
+		turnover:   v.Turnover,
+	}
+
+	for _, t := range []struct {
+		dst *time.Time
+		src *time.Time
+	}{
+		{&c.endTime, v.EndTime},
+		{&c.openTime, v.OpenTime},
+		{&c.highTime, v.HighTime},
+		{&c.lowTime, v.LowTime},
+		{&c.closeTime, v.CloseTime},
+	} {
+		if t.src != nil {
+			*t.dst = *t.src
+		}
+	}
+
+	return nil
+}
+
+// timeOrNil returns pointer to t, or nil if t is zero,
+// so zero times are omitted from JSON.
+func timeOrNil(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+
+	return &t
+}
@@ -9,6 +9,7 @@ import (
 
 func TestCandle_Internal_AddTrade(t *testing.T) {
 	defaultTime, _ := time.Parse(time.RFC3339, "2006-01-02T15:04:05Z")
+	tradeTime, _ := time.Parse(timeLayout, "2019-01-30 06:59:45.000249")
 
 	type args struct {
 		c *Candle
@@ -26,7 +27,7 @@ func TestCandle_Internal_AddTrade(t *testing.T) {
 				c: &Candle{},
 				t: Trade{},
 			},
-			want: &Candle{},
+			want: &Candle{trades: 1},
 		},
 		{
 			name: "non-empty values, max price",
@@ -48,6 +49,11 @@ func TestCandle_Internal_AddTrade(t *testing.T) {
 				maxPrice:   300.0,
 				minPrice:   50.0,
 				closePrice: 300.0,
+				trades:     1,
+				volume:     60,
+				turnover:   18000.0,
+				highTime:   tradeTime,
+				closeTime:  tradeTime,
 			},
 		},
 		{
@@ -70,6 +76,41 @@ func TestCandle_Internal_AddTrade(t *testing.T) {
 				maxPrice:   200.0,
 				minPrice:   25.0,
 				closePrice: 25.0,
+				trades:     1,
+				volume:     60,
+				turnover:   1500.0,
+				lowTime:    tradeTime,
+				closeTime:  tradeTime,
+			},
+		},
+		{
+			name: "order flow volumes",
+			args: args{
+				c: &Candle{
+					t:          ticker("TICKER"),
+					startTime:  defaultTime,
+					openPrice:  100.0,
+					maxPrice:   200.0,
+					minPrice:   50.0,
+					closePrice: 150.0,
+					buyVolume:  10,
+					sellVolume: 20,
+				},
+				t: MustTradeFromString("TICKER,150.0,60,2019-01-30 06:59:45.000249,S"),
+			},
+			want: &Candle{
+				t:          ticker("TICKER"),
+				startTime:  defaultTime,
+				openPrice:  100.0,
+				maxPrice:   200.0,
+				minPrice:   50.0,
+				closePrice: 150.0,
+				buyVolume:  10,
+				sellVolume: 80,
+				trades:     1,
+				volume:     60,
+				turnover:   9000.0,
+				closeTime:  tradeTime,
 			},
 		},
 	}
@@ -1,6 +1,7 @@
 package candles_test
 
 import (
+	"encoding/json"
 	"testing"
 	"time"
 
@@ -97,3 +98,193 @@ func TestCandle_AddTrade(t *testing.T) {
 		})
 	}
 }
+
+func TestCandle_JSON(t *testing.T) {
+	defaultTime, _ := time.Parse(time.RFC3339, "2006-01-02T15:04:05Z")
+
+	c := candles.New(candles.MustTradeFromString("TICKER,213.8,100,2019-01-30 06:59:45.000249"), defaultTime)
+	c.AddTrade(candles.MustTradeFromString("TICKER,0.1,100,2019-01-30 06:59:46.000249"))
+
+	data, err := json.Marshal(c)
+	assert.NoError(t, err)
+	assert.Equal(t,
+		`{"ticker":"TICKER","start_time":"2006-01-02T15:04:05Z","open":213.8,"high":213.8,"low":0.1,"close":0.1,`+
+			`"trades":2,"volume":200,"turnover":21390,`+
+			`"open_time":"2019-01-30T06:59:45.000249Z","high_time":"2019-01-30T06:59:45.000249Z",`+
+			`"low_time":"2019-01-30T06:59:46.000249Z","close_time":"2019-01-30T06:59:46.000249Z"}`,
+		string(data),
+	)
+
+	var got candles.Candle
+	assert.NoError(t, json.Unmarshal(data, &got))
+	assert.Equal(t, *c, got)
+}
+
+func TestCandleFromString(t *testing.T) {
+	defaultTime, _ := time.Parse(time.RFC3339, "2006-01-02T15:04:05Z")
+
+	tests := []struct {
+		name    string
+		s       string
+		want    *candles.Candle
+		wantErr error
+	}{
+		{
+			name: "success",
+			s:    "TICKER,2006-01-02T15:04:05Z,213.800000,213.800000,213.800000,213.800000",
+			want: candles.New(
+				candles.MustTradeFromString("TICKER,213.8,100,2019-01-30 06:59:45.000249"),
+				defaultTime,
+			),
+		},
+		{
+			name:    "invalid values count",
+			s:       "TICKER,2006-01-02T15:04:05Z,213.800000",
+			wantErr: candles.ErrInvalidValue,
+		},
+		{
+			name:    "invalid ticker",
+			s:       ",2006-01-02T15:04:05Z,213.800000,213.800000,213.800000,213.800000",
+			wantErr: candles.ErrInvalidTicker,
+		},
+		{
+			name:    "invalid time",
+			s:       "TICKER,2006-01-02 15:04:05,213.800000,213.800000,213.800000,213.800000",
+			wantErr: candles.ErrInvalidTime,
+		},
+		{
+			name:    "invalid price",
+			s:       "TICKER,2006-01-02T15:04:05Z,213.800000,213.800000,high,213.800000",
+			wantErr: candles.ErrInvalidPrice,
+		},
+		{
+			name: "invalid trade time",
+			s: "TICKER,2006-01-02T15:04:05Z,213.800000,213.800000,213.800000,213.800000," +
+				"2006-01-02T15:04:05Z,2006-01-02T15:04:05Z,2006-01-02T15:04:05Z,15:04:05",
+			wantErr: candles.ErrInvalidTime,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := candles.CandleFromString(test.s)
+			assert.Equal(t, test.wantErr, err)
+
+			if test.want != nil {
+				assert.Equal(t, test.want.String(), got.String())
+				assert.Equal(t, test.s, got.String())
+			}
+		})
+	}
+}
+
+func TestCandle_OrderFlowString(t *testing.T) {
+	defaultTime, _ := time.Parse(time.RFC3339, "2006-01-02T15:04:05Z")
+
+	tests := []struct {
+		name   string
+		trades []string
+		want   string
+	}{
+		{
+			name:   "no sides",
+			trades: []string{"TICKER,1,10,2019-01-30 06:59:45"},
+			want:   "0,0,0",
+		},
+		{
+			name: "buy and sell trades",
+			trades: []string{
+				"TICKER,1,10,2019-01-30 06:59:45,B",
+				"TICKER,1,25,2019-01-30 06:59:46,S",
+				"TICKER,1,5,2019-01-30 06:59:47,BUY",
+				"TICKER,1,100,2019-01-30 06:59:48",
+			},
+			want: "15,25,-10",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := candles.New(candles.MustTradeFromString(test.trades[0]), defaultTime)
+			for _, s := range test.trades[1:] {
+				c.AddTrade(candles.MustTradeFromString(s))
+			}
+
+			assert.Equal(t, test.want, c.OrderFlowString())
+
+			got, err := candles.CandleFromString(c.String() + "," + c.OrderFlowString())
+			assert.NoError(t, err)
+			assert.Equal(t, c.String(), got.String())
+			assert.Equal(t, c.OrderFlowString(), got.OrderFlowString())
+		})
+	}
+}
+
+func TestCandle_TradeTimesString(t *testing.T) {
+	defaultTime, _ := time.Parse(time.RFC3339, "2006-01-02T15:04:05Z")
+
+	c := candles.New(candles.MustTradeFromString("TICKER,100,10,2019-01-30 06:59:45.000249"), defaultTime)
+	for _, s := range []string{
+		"TICKER,200,10,2019-01-30 06:59:46",
+		"TICKER,50,10,2019-01-30 06:59:47",
+		"TICKER,200,10,2019-01-30 06:59:48",
+		"TICKER,150,10,2019-01-30 06:59:49.5",
+	} {
+		c.AddTrade(candles.MustTradeFromString(s))
+	}
+
+	want := "2019-01-30T06:59:45.000249Z,2019-01-30T06:59:46Z,2019-01-30T06:59:47Z,2019-01-30T06:59:49.5Z"
+	assert.Equal(t, want, c.TradeTimesString())
+
+	for _, s := range []string{
+		c.String() + "," + c.TradeTimesString(),
+		c.String() + "," + c.OrderFlowString() + "," + c.TradeTimesString(),
+	} {
+		got, err := candles.CandleFromString(s)
+		assert.NoError(t, err)
+		assert.Equal(t, want, got.TradeTimesString())
+	}
+}
+
+func TestCandle_getters(t *testing.T) {
+	iStart := time.Date(2019, 1, 30, 10, 0, 0, 0, time.UTC)
+
+	c := candles.New(candles.MustTradeFromString("TICKER,100,10,2019-01-30 10:00:01,B"), iStart)
+	c.AddTrade(candles.MustTradeFromString("TICKER,120,5,2019-01-30 10:00:02,S"))
+	c.AddTrade(candles.MustTradeFromString("TICKER,90,5,2019-01-30 10:00:03"))
+
+	assert.Equal(t, "TICKER", c.Ticker())
+	assert.Equal(t, iStart, c.StartTime())
+	assert.Equal(t, 100.0, c.OpenPrice())
+	assert.Equal(t, 120.0, c.HighPrice())
+	assert.Equal(t, 90.0, c.LowPrice())
+	assert.Equal(t, 90.0, c.ClosePrice())
+	assert.Equal(t, 3, c.Trades())
+	assert.Equal(t, 20, c.Volume())
+	assert.Equal(t, 2050.0, c.Turnover())
+	assert.Equal(t, 10, c.BuyVolume())
+	assert.Equal(t, 5, c.SellVolume())
+}
+
+func TestCandle_Equal(t *testing.T) {
+	iStart := time.Date(2019, 1, 30, 10, 0, 0, 0, time.UTC)
+
+	a := candles.New(candles.MustTradeFromString("TICKER,100,10,2019-01-30 10:00:01"), iStart)
+	b := candles.New(candles.MustTradeFromString("TICKER,100,10,2019-01-30 10:00:01"), iStart.In(time.FixedZone("MSK", 3*60*60)))
+	assert.True(t, a.Equal(b))
+
+	b.AddTrade(candles.MustTradeFromString("TICKER,100,10,2019-01-30 10:00:02"))
+	assert.False(t, a.Equal(b))
+}
+
+func TestCompareCandles(t *testing.T) {
+	iStart := time.Date(2019, 1, 30, 10, 0, 0, 0, time.UTC)
+
+	a := candles.New(candles.MustTradeFromString("AAPL,100,10,2019-01-30 10:00:01"), iStart)
+	b := candles.New(candles.MustTradeFromString("MSFT,100,10,2019-01-30 10:00:01"), iStart)
+	c := candles.New(candles.MustTradeFromString("AAPL,100,10,2019-01-30 10:05:01"), iStart.Add(5*time.Minute))
+
+	assert.Equal(t, -1, candles.CompareCandles(a, b))
+	assert.Equal(t, 1, candles.CompareCandles(c, b))
+	assert.Equal(t, 0, candles.CompareCandles(a, a))
+}
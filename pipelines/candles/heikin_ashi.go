@@ -0,0 +1,72 @@
+package candles
+
+import (
+	"encoding/json"
+	"math"
+)
+
+// HeikinAshi transforms stream of candles into Heikin-Ashi candles
+// separately for each ticker.
+type HeikinAshi struct {
+	prev map[ticker]haCandle
+}
+
+// haCandle contains values of previous Heikin-Ashi candle
+// needed to build the next one.
+type haCandle struct {
+	Open  float64 `json:"open"`
+	Close float64 `json:"close"`
+}
+
+// NewHeikinAshi creates new HeikinAshi transform.
+func NewHeikinAshi() *HeikinAshi {
+	return &HeikinAshi{
+		prev: make(map[ticker]haCandle),
+	}
+}
+
+// Transform returns Heikin-Ashi candle for the next candle of its ticker.
+func (ha *HeikinAshi) Transform(c Candle) Candle {
+	const pricesCount = 4
+
+	out := c
+	out.closePrice = (c.openPrice + c.maxPrice + c.minPrice + c.closePrice) / pricesCount
+
+	if prev, ok := ha.prev[c.t]; ok {
+		out.openPrice = (prev.Open + prev.Close) / 2
+	} else {
+		out.openPrice = (c.openPrice + c.closePrice) / 2
+	}
+
+	out.maxPrice = math.Max(c.maxPrice, math.Max(out.openPrice, out.closePrice))
+	out.minPrice = math.Min(c.minPrice, math.Min(out.openPrice, out.closePrice))
+
+	ha.prev[c.t] = haCandle{Open: out.openPrice, Close: out.closePrice}
+
+	return out
+}
+
+// MarshalJSON implements json.Marshaler.
+func (ha *HeikinAshi) MarshalJSON() ([]byte, error) {
+	v := make(map[string]haCandle, len(ha.prev))
+	for t, c := range ha.prev {
+		v[string(t)] = c
+	}
+
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (ha *HeikinAshi) UnmarshalJSON(data []byte) error {
+	var v map[string]haCandle
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	ha.prev = make(map[ticker]haCandle, len(v))
+	for t, c := range v {
+		ha.prev[ticker(t)] = c
+	}
+
+	return nil
+}
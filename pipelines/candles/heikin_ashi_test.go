@@ -0,0 +1,90 @@
+package candles_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/candles/pipelines/candles"
+)
+
+func TestHeikinAshi_Transform(t *testing.T) {
+	tests := []struct {
+		name    string
+		candles []string
+		want    []string
+	}{
+		{
+			name: "first candle",
+			candles: []string{
+				"TICKER,2019-01-30T11:00:00Z,100.000000,200.000000,50.000000,150.000000",
+			},
+			want: []string{
+				"TICKER,2019-01-30T11:00:00Z,125.000000,200.000000,50.000000,125.000000",
+			},
+		},
+		{
+			name: "next candles",
+			candles: []string{
+				"TICKER,2019-01-30T11:00:00Z,100.000000,200.000000,50.000000,150.000000",
+				"TICKER,2019-01-30T11:05:00Z,150.000000,160.000000,140.000000,150.000000",
+				"TICKER,2019-01-30T11:10:00Z,150.000000,300.000000,150.000000,300.000000",
+			},
+			want: []string{
+				"TICKER,2019-01-30T11:00:00Z,125.000000,200.000000,50.000000,125.000000",
+				"TICKER,2019-01-30T11:05:00Z,125.000000,160.000000,125.000000,150.000000",
+				"TICKER,2019-01-30T11:10:00Z,137.500000,300.000000,137.500000,225.000000",
+			},
+		},
+		{
+			name: "tickers are independent",
+			candles: []string{
+				"TICKER_ONE,2019-01-30T11:00:00Z,100.000000,200.000000,50.000000,150.000000",
+				"TICKER_TWO,2019-01-30T11:00:00Z,10.000000,10.000000,10.000000,10.000000",
+				"TICKER_TWO,2019-01-30T11:05:00Z,20.000000,20.000000,20.000000,20.000000",
+			},
+			want: []string{
+				"TICKER_ONE,2019-01-30T11:00:00Z,125.000000,200.000000,50.000000,125.000000",
+				"TICKER_TWO,2019-01-30T11:00:00Z,10.000000,10.000000,10.000000,10.000000",
+				"TICKER_TWO,2019-01-30T11:05:00Z,10.000000,20.000000,10.000000,20.000000",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ha := candles.NewHeikinAshi()
+
+			got := make([]string, 0, len(test.candles))
+			for _, s := range test.candles {
+				c, err := candles.CandleFromString(s)
+				assert.NoError(t, err)
+
+				hc := ha.Transform(c)
+				got = append(got, hc.String())
+			}
+
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
+
+func TestHeikinAshi_JSON(t *testing.T) {
+	first, err := candles.CandleFromString("TICKER,2019-01-30T11:00:00Z,100.000000,200.000000,50.000000,150.000000")
+	assert.NoError(t, err)
+
+	next, err := candles.CandleFromString("TICKER,2019-01-30T11:05:00Z,150.000000,160.000000,140.000000,150.000000")
+	assert.NoError(t, err)
+
+	ha := candles.NewHeikinAshi()
+	ha.Transform(first)
+
+	data, err := json.Marshal(ha)
+	assert.NoError(t, err)
+
+	restored := candles.NewHeikinAshi()
+	assert.NoError(t, json.Unmarshal(data, restored))
+
+	assert.Equal(t, ha.Transform(next), restored.Transform(next))
+}
@@ -0,0 +1,512 @@
+package candles
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidIndicator is returned for unknown indicator kinds and invalid parameters.
+var ErrInvalidIndicator = errors.New("invalid indicator")
+
+// IndicatorKind describes technical indicator computed from closed candles.
+type IndicatorKind int
+
+// IndicatorKind values.
+const (
+	// SMA is simple moving average of close prices, parameters: period.
+	SMA IndicatorKind = iota + 1
+	// EMA is exponential moving average of close prices, parameters: period.
+	EMA
+	// RSI is relative strength index with Wilder smoothing, parameters: period.
+	RSI
+	// MACD outputs MACD line, signal line and histogram,
+	// parameters: fast period, slow period, signal period.
+	MACD
+	// Bollinger outputs middle, upper and lower bands,
+	// parameters: period, width in standard deviations.
+	Bollinger
+	// ATR is average true range with Wilder smoothing, parameters: period.
+	ATR
+	// VWAP is volume weighted average price since the start of the trading session,
+	// which is set by Indicators.SetSessionStart.
+	VWAP
+)
+
+// defaultParams contains default parameters of indicators.
+var defaultParams = map[IndicatorKind][]float64{
+	SMA:       {20},
+	EMA:       {20},
+	RSI:       {14},
+	MACD:      {12, 26, 9},
+	Bollinger: {20, 2},
+	ATR:       {14},
+	VWAP:      {},
+}
+
+// ParseIndicatorKind parses IndicatorKind from its string value.
+func ParseIndicatorKind(s string) (IndicatorKind, error) {
+	for k := SMA; k <= VWAP; k++ {
+		if k.String() == s {
+			return k, nil
+		}
+	}
+
+	return 0, ErrInvalidIndicator
+}
+
+// String returns string value of IndicatorKind.
+func (k IndicatorKind) String() string {
+	switch k {
+	case SMA:
+		return "sma"
+	case EMA:
+		return "ema"
+	case RSI:
+		return "rsi"
+	case MACD:
+		return "macd"
+	case Bollinger:
+		return "bb"
+	case ATR:
+		return "atr"
+	case VWAP:
+		return "vwap"
+	default:
+		return ""
+	}
+}
+
+// columns returns count of values of indicator.
+func (k IndicatorKind) columns() int {
+	if k == MACD || k == Bollinger {
+		return 3
+	}
+
+	return 1
+}
+
+// IndicatorSpec describes indicator computed by Indicators.
+type IndicatorSpec struct {
+	Kind IndicatorKind
+	// Params contains indicator parameters, defaults are used for missing ones.
+	Params []float64
+}
+
+// params returns spec parameters completed with defaults.
+func (s IndicatorSpec) params() ([]float64, error) {
+	def, ok := defaultParams[s.Kind]
+	if !ok || len(s.Params) > len(def) {
+		return nil, ErrInvalidIndicator
+	}
+
+	p := append([]float64(nil), def...)
+	copy(p, s.Params)
+
+	for i, v := range p {
+		// all parameters are periods, except for Bollinger bands width.
+		isPeriod := s.Kind != Bollinger || i == 0
+		if v <= 0 || isPeriod && v != math.Trunc(v) {
+			return nil, ErrInvalidIndicator
+		}
+	}
+
+	return p, nil
+}
+
+// Indicators computes technical indicators from stream of closed candles
+// separately for each ticker.
+// Values are output only once indicator has enough candles,
+// moving averages are seeded with simple average of the first period values.
+type Indicators struct {
+	specs   []IndicatorSpec
+	params  [][]float64
+	columns int
+	data    map[ticker][]indicator
+	// sessionStart is the hour of day trading session starts at.
+	sessionStart int
+}
+
+// indicator computes values of a single indicator for a single ticker.
+// State of indicator is marshaled to JSON by its exported fields.
+type indicator interface {
+	add(c *Candle) []float64
+}
+
+// NewIndicators creates new Indicators computing provided indicators.
+func NewIndicators(specs ...IndicatorSpec) (*Indicators, error) {
+	ind := &Indicators{
+		specs:  specs,
+		params: make([][]float64, 0, len(specs)),
+		data:   make(map[ticker][]indicator),
+	}
+
+	for _, s := range specs {
+		p, err := s.params()
+		if err != nil {
+			return nil, err
+		}
+
+		ind.params = append(ind.params, p)
+		ind.columns += s.Kind.columns()
+	}
+
+	return ind, nil
+}
+
+// Add adds closed candle to indicators of its ticker.
+// Returns comma separated indicator values, not yet available values are empty.
+func (ind *Indicators) Add(c *Candle) string {
+	state, ok := ind.data[c.t]
+	if !ok {
+		state = ind.newState()
+		ind.data[c.t] = state
+	}
+
+	values := make([]string, 0, len(state))
+
+	for _, in := range state {
+		for _, v := range in.add(c) {
+			values = append(values, formatIndicatorValue(v))
+		}
+	}
+
+	return strings.Join(values, ",")
+}
+
+// Empty returns comma separated empty indicator values.
+func (ind *Indicators) Empty() string {
+	if ind.columns == 0 {
+		return ""
+	}
+
+	return strings.Repeat(",", ind.columns-1)
+}
+
+// MarshalJSON implements json.Marshaler.
+// Only state of indicators is marshaled, specs are not.
+func (ind *Indicators) MarshalJSON() ([]byte, error) {
+	v := make(map[string][]indicator, len(ind.data))
+	for t, state := range ind.data {
+		v[string(t)] = state
+	}
+
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+// Specs of indicators are kept.
+func (ind *Indicators) UnmarshalJSON(data []byte) error {
+	var v map[string][]json.RawMessage
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	ind.data = make(map[ticker][]indicator, len(v))
+
+	for t, raw := range v {
+		if len(raw) != len(ind.specs) {
+			return ErrInvalidIndicator
+		}
+
+		state := ind.newState()
+		for i := range state {
+			if err := json.Unmarshal(raw[i], state[i]); err != nil {
+				return err
+			}
+		}
+
+		ind.data[ticker(t)] = state
+	}
+
+	return nil
+}
+
+// SetSessionStart sets hour of day trading session starts at, midnight by default.
+// Indicators accumulated over session, e.g. VWAP, are reset at session start.
+// Must be called before Add.
+func (ind *Indicators) SetSessionStart(hour int) {
+	ind.sessionStart = hour
+}
+
+// newState creates indicators state of a single ticker.
+func (ind *Indicators) newState() []indicator {
+	state := make([]indicator, 0, len(ind.specs))
+
+	for i, s := range ind.specs {
+		p := ind.params[i]
+
+		switch s.Kind {
+		case SMA:
+			state = append(state, &smaIndicator{Window: newWindow(int(p[0]))})
+		case EMA:
+			state = append(state, &emaIndicator{Avg: newAverage(int(p[0]), false)})
+		case RSI:
+			state = append(state, &rsiIndicator{
+				Gain: newAverage(int(p[0]), true),
+				Loss: newAverage(int(p[0]), true),
+			})
+		case MACD:
+			state = append(state, &macdIndicator{
+				Fast:   newAverage(int(p[0]), false),
+				Slow:   newAverage(int(p[1]), false),
+				Signal: newAverage(int(p[2]), false),
+			})
+		case Bollinger:
+			state = append(state, &bollingerIndicator{Window: newWindow(int(p[0])), Width: p[1]})
+		case ATR:
+			state = append(state, &atrIndicator{Avg: newAverage(int(p[0]), true)})
+		case VWAP:
+			state = append(state, &vwapIndicator{start: ind.sessionStart})
+		}
+	}
+
+	return state
+}
+
+// formatIndicatorValue formats indicator value as candle prices are,
+// not available value is formatted as empty string.
+func formatIndicatorValue(v float64) string {
+	if math.IsNaN(v) {
+		return ""
+	}
+
+	return strconv.FormatFloat(v, 'f', 6, 64)
+}
+
+// average is exponential moving average seeded with simple average
+// of the first period values.
+// Wilder average uses 1/period smoothing factor instead of 2/(period+1).
+type average struct {
+	Period int     `json:"period"`
+	Wilder bool    `json:"wilder,omitempty"`
+	Count  int     `json:"count"`
+	Value  float64 `json:"value"`
+}
+
+// newAverage creates new average of provided period.
+func newAverage(period int, wilder bool) average {
+	return average{Period: period, Wilder: wilder}
+}
+
+// add adds value to average and returns the average,
+// which is NaN until period values are added.
+func (a *average) add(v float64) float64 {
+	if a.Count < a.Period {
+		a.Count++
+		a.Value += (v - a.Value) / float64(a.Count)
+
+		if a.Count < a.Period {
+			return math.NaN()
+		}
+
+		return a.Value
+	}
+
+	alpha := 2 / float64(a.Period+1)
+	if a.Wilder {
+		alpha = 1 / float64(a.Period)
+	}
+
+	a.Value += alpha * (v - a.Value)
+
+	return a.Value
+}
+
+// window contains the last values added to it.
+type window struct {
+	Size   int       `json:"size"`
+	Values []float64 `json:"values"`
+}
+
+// newWindow creates new window of provided size.
+func newWindow(size int) window {
+	return window{Size: size, Values: make([]float64, 0, size)}
+}
+
+// add adds value to window, dropping the oldest value if window is full.
+// Reports whether window is full.
+func (w *window) add(v float64) bool {
+	if len(w.Values) == w.Size {
+		copy(w.Values, w.Values[1:])
+		w.Values = w.Values[:w.Size-1]
+	}
+
+	w.Values = append(w.Values, v)
+
+	return len(w.Values) == w.Size
+}
+
+// mean returns mean of window values.
+func (w *window) mean() float64 {
+	sum := 0.0
+	for _, v := range w.Values {
+		sum += v
+	}
+
+	return sum / float64(len(w.Values))
+}
+
+// smaIndicator computes simple moving average.
+type smaIndicator struct {
+	Window window `json:"window"`
+}
+
+func (in *smaIndicator) add(c *Candle) []float64 {
+	if !in.Window.add(c.closePrice) {
+		return []float64{math.NaN()}
+	}
+
+	return []float64{in.Window.mean()}
+}
+
+// emaIndicator computes exponential moving average.
+type emaIndicator struct {
+	Avg average `json:"avg"`
+}
+
+func (in *emaIndicator) add(c *Candle) []float64 {
+	return []float64{in.Avg.add(c.closePrice)}
+}
+
+// rsiIndicator computes relative strength index.
+type rsiIndicator struct {
+	Gain      average `json:"gain"`
+	Loss      average `json:"loss"`
+	PrevClose float64 `json:"prev_close"`
+	Started   bool    `json:"started"`
+}
+
+func (in *rsiIndicator) add(c *Candle) []float64 {
+	const (
+		maxRSI     = 100
+		neutralRSI = 50
+	)
+
+	if !in.Started {
+		in.Started = true
+		in.PrevClose = c.closePrice
+
+		return []float64{math.NaN()}
+	}
+
+	change := c.closePrice - in.PrevClose
+	in.PrevClose = c.closePrice
+
+	gain := in.Gain.add(math.Max(change, 0))
+	loss := in.Loss.add(math.Max(-change, 0))
+
+	switch {
+	case math.IsNaN(gain):
+		return []float64{math.NaN()}
+	case gain == 0 && loss == 0:
+		// price doesn't change, so neither side is stronger.
+		return []float64{neutralRSI}
+	case loss == 0:
+		return []float64{maxRSI}
+	default:
+		return []float64{maxRSI - maxRSI/(1+gain/loss)}
+	}
+}
+
+// macdIndicator computes MACD line, signal line and histogram.
+type macdIndicator struct {
+	Fast   average `json:"fast"`
+	Slow   average `json:"slow"`
+	Signal average `json:"signal"`
+}
+
+func (in *macdIndicator) add(c *Candle) []float64 {
+	fast := in.Fast.add(c.closePrice)
+	slow := in.Slow.add(c.closePrice)
+
+	if math.IsNaN(fast) || math.IsNaN(slow) {
+		return []float64{math.NaN(), math.NaN(), math.NaN()}
+	}
+
+	macd := fast - slow
+	signal := in.Signal.add(macd)
+
+	return []float64{macd, signal, macd - signal}
+}
+
+// bollingerIndicator computes middle, upper and lower Bollinger bands.
+type bollingerIndicator struct {
+	Window window  `json:"window"`
+	Width  float64 `json:"width"`
+}
+
+func (in *bollingerIndicator) add(c *Candle) []float64 {
+	if !in.Window.add(c.closePrice) {
+		return []float64{math.NaN(), math.NaN(), math.NaN()}
+	}
+
+	mean := in.Window.mean()
+
+	variance := 0.0
+	for _, v := range in.Window.Values {
+		variance += (v - mean) * (v - mean)
+	}
+
+	dev := in.Width * math.Sqrt(variance/float64(len(in.Window.Values)))
+
+	return []float64{mean, mean + dev, mean - dev}
+}
+
+// atrIndicator computes average true range.
+type atrIndicator struct {
+	Avg       average `json:"avg"`
+	PrevClose float64 `json:"prev_close"`
+	Started   bool    `json:"started"`
+}
+
+func (in *atrIndicator) add(c *Candle) []float64 {
+	tr := c.maxPrice - c.minPrice
+	if in.Started {
+		tr = math.Max(tr, math.Max(math.Abs(c.maxPrice-in.PrevClose), math.Abs(c.minPrice-in.PrevClose)))
+	}
+
+	in.Started = true
+	in.PrevClose = c.closePrice
+
+	return []float64{in.Avg.add(tr)}
+}
+
+// vwapIndicator computes volume weighted average price since the start of the candle session.
+type vwapIndicator struct {
+	Session  time.Time `json:"session"`
+	Turnover float64   `json:"turnover"`
+	Volume   int       `json:"volume"`
+
+	// start is the hour of day session starts at.
+	start int
+}
+
+func (in *vwapIndicator) add(c *Candle) []float64 {
+	if s := sessionStart(c.startTime, in.start); !s.Equal(in.Session) {
+		in.Session, in.Turnover, in.Volume = s, 0, 0
+	}
+
+	in.Turnover += c.turnover
+	in.Volume += c.volume
+
+	if in.Volume == 0 {
+		return []float64{math.NaN()}
+	}
+
+	return []float64{in.Turnover / float64(in.Volume)}
+}
+
+// sessionStart returns start time of session t belongs to,
+// sessions start every day at provided hour.
+func sessionStart(t time.Time, hour int) time.Time {
+	start := time.Date(t.Year(), t.Month(), t.Day(), hour, 0, 0, 0, t.Location())
+	if t.Before(start) {
+		start = start.AddDate(0, 0, -1)
+	}
+
+	return start
+}
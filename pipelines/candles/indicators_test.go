@@ -0,0 +1,223 @@
+package candles_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/candles/pipelines/candles"
+)
+
+// candleFromTrades builds candle of provided trades.
+func candleFromTrades(trades ...string) *candles.Candle {
+	first := candles.MustTradeFromString(trades[0])
+	c := candles.New(first, first.Timestamp)
+
+	for _, s := range trades[1:] {
+		c.AddTrade(candles.MustTradeFromString(s))
+	}
+
+	return c
+}
+
+func TestIndicators_Add(t *testing.T) {
+	tests := []struct {
+		name         string
+		spec         candles.IndicatorSpec
+		sessionStart int
+		candles      [][]string
+		want         []string
+	}{
+		{
+			name: "sma",
+			spec: candles.IndicatorSpec{Kind: candles.SMA, Params: []float64{2}},
+			candles: [][]string{
+				{"TICKER,1,1,2019-01-30 11:00:00"},
+				{"TICKER,2,1,2019-01-30 11:05:00"},
+				{"TICKER,3,1,2019-01-30 11:10:00"},
+			},
+			want: []string{"", "1.500000", "2.500000"},
+		},
+		{
+			name: "ema",
+			spec: candles.IndicatorSpec{Kind: candles.EMA, Params: []float64{2}},
+			candles: [][]string{
+				{"TICKER,1,1,2019-01-30 11:00:00"},
+				{"TICKER,2,1,2019-01-30 11:05:00"},
+				{"TICKER,4,1,2019-01-30 11:10:00"},
+			},
+			want: []string{"", "1.500000", "3.166667"},
+		},
+		{
+			name: "rsi",
+			spec: candles.IndicatorSpec{Kind: candles.RSI, Params: []float64{2}},
+			candles: [][]string{
+				{"TICKER,1,1,2019-01-30 11:00:00"},
+				{"TICKER,2,1,2019-01-30 11:05:00"},
+				{"TICKER,1,1,2019-01-30 11:10:00"},
+				{"TICKER,3,1,2019-01-30 11:15:00"},
+			},
+			want: []string{"", "", "50.000000", "83.333333"},
+		},
+		{
+			name: "rsi of unchanged prices",
+			spec: candles.IndicatorSpec{Kind: candles.RSI, Params: []float64{2}},
+			candles: [][]string{
+				{"TICKER,1,1,2019-01-30 11:00:00"},
+				{"TICKER,1,1,2019-01-30 11:05:00"},
+				{"TICKER,1,1,2019-01-30 11:10:00"},
+			},
+			want: []string{"", "", "50.000000"},
+		},
+		{
+			name: "macd",
+			spec: candles.IndicatorSpec{Kind: candles.MACD, Params: []float64{1, 2, 1}},
+			candles: [][]string{
+				{"TICKER,1,1,2019-01-30 11:00:00"},
+				{"TICKER,2,1,2019-01-30 11:05:00"},
+				{"TICKER,3,1,2019-01-30 11:10:00"},
+			},
+			want: []string{",,", "0.500000,0.500000,0.000000", "0.500000,0.500000,0.000000"},
+		},
+		{
+			name: "bollinger bands",
+			spec: candles.IndicatorSpec{Kind: candles.Bollinger, Params: []float64{2}},
+			candles: [][]string{
+				{"TICKER,1,1,2019-01-30 11:00:00"},
+				{"TICKER,3,1,2019-01-30 11:05:00"},
+			},
+			want: []string{",,", "2.000000,4.000000,0.000000"},
+		},
+		{
+			name: "atr",
+			spec: candles.IndicatorSpec{Kind: candles.ATR, Params: []float64{2}},
+			candles: [][]string{
+				{"TICKER,10,1,2019-01-30 11:00:00", "TICKER,8,1,2019-01-30 11:01:00", "TICKER,9,1,2019-01-30 11:02:00"},
+				{"TICKER,11,1,2019-01-30 11:05:00", "TICKER,12,1,2019-01-30 11:06:00"},
+				{"TICKER,12,1,2019-01-30 11:10:00", "TICKER,10,1,2019-01-30 11:11:00", "TICKER,11,1,2019-01-30 11:12:00"},
+			},
+			want: []string{"", "2.500000", "2.250000"},
+		},
+		{
+			name: "vwap is reset every day",
+			spec: candles.IndicatorSpec{Kind: candles.VWAP},
+			candles: [][]string{
+				{"TICKER,10,1,2019-01-30 11:00:00"},
+				{"TICKER,20,3,2019-01-30 11:05:00"},
+				{"TICKER,30,1,2019-01-31 11:00:00"},
+			},
+			want: []string{"10.000000", "17.500000", "30.000000"},
+		},
+		{
+			name:         "vwap is reset on session start",
+			spec:         candles.IndicatorSpec{Kind: candles.VWAP},
+			sessionStart: 10,
+			candles: [][]string{
+				{"TICKER,10,1,2019-01-30 11:00:00"},
+				{"TICKER,20,3,2019-01-31 01:00:00"},
+				{"TICKER,30,1,2019-01-31 10:00:00"},
+			},
+			want: []string{"10.000000", "17.500000", "30.000000"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ind, err := candles.NewIndicators(test.spec)
+			assert.NoError(t, err)
+
+			ind.SetSessionStart(test.sessionStart)
+
+			got := make([]string, 0, len(test.candles))
+			for _, trades := range test.candles {
+				got = append(got, ind.Add(candleFromTrades(trades...)))
+			}
+
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
+
+func TestNewIndicators(t *testing.T) {
+	tests := []struct {
+		name    string
+		specs   []candles.IndicatorSpec
+		wantErr bool
+	}{
+		{
+			name: "defaults",
+			specs: []candles.IndicatorSpec{
+				{Kind: candles.SMA}, {Kind: candles.MACD}, {Kind: candles.VWAP},
+			},
+		},
+		{
+			name:  "fractional bollinger width",
+			specs: []candles.IndicatorSpec{{Kind: candles.Bollinger, Params: []float64{20, 1.5}}},
+		},
+		{
+			name:    "unknown kind",
+			specs:   []candles.IndicatorSpec{{Kind: 100}},
+			wantErr: true,
+		},
+		{
+			name:    "too many params",
+			specs:   []candles.IndicatorSpec{{Kind: candles.SMA, Params: []float64{20, 30}}},
+			wantErr: true,
+		},
+		{
+			name:    "fractional period",
+			specs:   []candles.IndicatorSpec{{Kind: candles.EMA, Params: []float64{2.5}}},
+			wantErr: true,
+		},
+		{
+			name:    "negative period",
+			specs:   []candles.IndicatorSpec{{Kind: candles.RSI, Params: []float64{-1}}},
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := candles.NewIndicators(test.specs...)
+			assert.Equal(t, test.wantErr, err != nil)
+		})
+	}
+}
+
+func TestIndicators_JSON(t *testing.T) {
+	specs := []candles.IndicatorSpec{
+		{Kind: candles.SMA, Params: []float64{2}},
+		{Kind: candles.RSI, Params: []float64{2}},
+		{Kind: candles.MACD, Params: []float64{1, 2, 1}},
+		{Kind: candles.ATR, Params: []float64{2}},
+		{Kind: candles.VWAP},
+	}
+
+	ind, err := candles.NewIndicators(specs...)
+	assert.NoError(t, err)
+
+	ind.Add(candleFromTrades("TICKER,1,1,2019-01-30 11:00:00"))
+	ind.Add(candleFromTrades("TICKER,2,1,2019-01-30 11:05:00"))
+
+	data, err := json.Marshal(ind)
+	assert.NoError(t, err)
+
+	restored, err := candles.NewIndicators(specs...)
+	assert.NoError(t, err)
+	assert.NoError(t, json.Unmarshal(data, restored))
+
+	next := candleFromTrades("TICKER,4,1,2019-01-30 11:10:00")
+	assert.Equal(t, ind.Add(next), restored.Add(next))
+}
+
+func TestIndicators_Empty(t *testing.T) {
+	ind, err := candles.NewIndicators(
+		candles.IndicatorSpec{Kind: candles.SMA},
+		candles.IndicatorSpec{Kind: candles.MACD},
+		candles.IndicatorSpec{Kind: candles.Bollinger},
+	)
+	assert.NoError(t, err)
+
+	assert.Equal(t, ",,,,,,", ind.Empty())
+}
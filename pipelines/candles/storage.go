@@ -1,6 +1,9 @@
 package candles
 
-import "time"
+import (
+	"sort"
+	"time"
+)
 
 type ticker string
 
@@ -21,16 +24,25 @@ func (cs *Storage) Len() int {
 	return len(cs.data)
 }
 
-// Candles returns candles for single interval.
+// Candles returns candles for single interval ordered by ticker.
 func (cs *Storage) Candles() []Candle {
 	out := make([]Candle, 0, len(cs.data))
 	for _, c := range cs.data {
 		out = append(out, *c)
 	}
 
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].t < out[j].t
+	})
+
 	return out
 }
 
+// Put puts candle to storage, replacing candle of the same ticker.
+func (cs *Storage) Put(c Candle) {
+	cs.data[c.t] = &c
+}
+
 // Clear clears storage.
 // Has to be called for each new interval.
 func (cs *Storage) Clear() {
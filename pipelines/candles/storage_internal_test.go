@@ -121,6 +121,13 @@ func TestStorage_Internal_AddTrade(t *testing.T) {
 						maxPrice:   100.0,
 						minPrice:   100.0,
 						closePrice: 100.0,
+						trades:     1,
+						volume:     10,
+						turnover:   1000.0,
+						openTime:   defaultTime,
+						highTime:   defaultTime,
+						lowTime:    defaultTime,
+						closeTime:  defaultTime,
 					},
 				},
 			},
@@ -155,6 +162,13 @@ func TestStorage_Internal_AddTrade(t *testing.T) {
 						maxPrice:   100.0,
 						minPrice:   100.0,
 						closePrice: 100.0,
+						trades:     1,
+						volume:     10,
+						turnover:   1000.0,
+						openTime:   defaultTime,
+						highTime:   defaultTime,
+						lowTime:    defaultTime,
+						closeTime:  defaultTime,
 					},
 				},
 			},
@@ -191,6 +205,11 @@ func TestStorage_Internal_AddTrade(t *testing.T) {
 						maxPrice:   200.0,
 						minPrice:   100.0,
 						closePrice: 200.0,
+						trades:     1,
+						volume:     10,
+						turnover:   2000.0,
+						highTime:   defaultTime,
+						closeTime:  defaultTime,
 					},
 				},
 			},
@@ -108,3 +108,24 @@ func TestStorage_Len(t *testing.T) {
 		})
 	}
 }
+
+func TestStorage_Put(t *testing.T) {
+	defaultTime, _ := time.Parse(time.RFC3339, "2006-01-02T15:04:05Z")
+
+	cs := candles.NewStorage()
+	cs.AddTrade(candles.MustTradeFromString("TICKER_TWO,100.000000,10,2019-01-30 06:59:45.000249"), defaultTime)
+	cs.AddTrade(candles.MustTradeFromString("TICKER_ONE,100.000000,10,2019-01-30 06:59:45.000249"), defaultTime)
+	cs.Put(*candles.New(candles.MustTradeFromString("TICKER_TWO,300.000000,10,2019-01-30 06:59:45.000249"), defaultTime))
+
+	want := []string{
+		"TICKER_ONE,2006-01-02T15:04:05Z,100.000000,100.000000,100.000000,100.000000",
+		"TICKER_TWO,2006-01-02T15:04:05Z,300.000000,300.000000,300.000000,300.000000",
+	}
+
+	got := make([]string, 0, cs.Len())
+	for _, c := range cs.Candles() {
+		got = append(got, c.String())
+	}
+
+	assert.Equal(t, want, got)
+}
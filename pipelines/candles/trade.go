@@ -1,13 +1,20 @@
 package candles
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
+	"math"
 	"strconv"
 	"strings"
 	"time"
 )
 
-const tradeDataLen = 4
+const (
+	tradeDataLen     = 4
+	tradeWithSideLen = 5
+	timeLayout       = "2006-01-02 15:04:05.999999"
+)
 
 var (
 	ErrInvalidTicker = errors.New("invalid ticker provided")
@@ -17,14 +24,181 @@ var (
 	ErrInvalidTime   = errors.New("invalid timestamp")
 )
 
+// Side describes aggressor side of a trade.
+type Side int8
+
+// Side values.
+const (
+	SideUnknown Side = iota
+	SideBuy
+	SideSell
+)
+
+// String returns string value of Side.
+func (s Side) String() string {
+	switch s {
+	case SideBuy:
+		return "B"
+	case SideSell:
+		return "S"
+	default:
+		return ""
+	}
+}
+
 // Trade contains data about trade deal.
+// Trades are created by NewTrade or parsed from "ticker,price,count,timestamp[,side]" lines.
 type Trade struct {
 	t         ticker
 	price     float64
 	count     int
+	side      Side
 	Timestamp time.Time
 }
 
+// NewTrade creates new Trade.
+// Ticker must be non-empty and must not contain commas,
+// price must be positive and finite, count must be positive and timestamp must be set.
+func NewTrade(tickerName string, price float64, count int, side Side, timestamp time.Time) (Trade, error) {
+	if tickerName == "" || strings.ContainsAny(tickerName, ",\n") {
+		return Trade{}, ErrInvalidTicker
+	}
+
+	if !(price > 0) || math.IsInf(price, 1) {
+		return Trade{}, ErrInvalidPrice
+	}
+
+	if count <= 0 {
+		return Trade{}, ErrInvalidCount
+	}
+
+	if timestamp.IsZero() {
+		return Trade{}, ErrInvalidTime
+	}
+
+	if side < SideUnknown || side > SideSell {
+		return Trade{}, ErrInvalidValue
+	}
+
+	return Trade{
+		t:         ticker(tickerName),
+		price:     price,
+		count:     count,
+		side:      side,
+		Timestamp: timestamp,
+	}, nil
+}
+
+// Ticker returns ticker of Trade.
+func (tr Trade) Ticker() string {
+	return string(tr.t)
+}
+
+// Price returns price of Trade.
+func (tr Trade) Price() float64 {
+	return tr.price
+}
+
+// Count returns count of traded units.
+func (tr Trade) Count() int {
+	return tr.count
+}
+
+// Side returns aggressor side of Trade.
+func (tr Trade) Side() Side {
+	return tr.side
+}
+
+// String returns Trade line, which can be parsed by TradeFromString.
+func (tr Trade) String() string {
+	s := string(tr.t) + "," +
+		strconv.FormatFloat(tr.price, 'f', -1, 64) + "," +
+		strconv.Itoa(tr.count) + "," +
+		tr.Timestamp.Format(timeLayout)
+
+	if tr.side != SideUnknown {
+		s += "," + tr.side.String()
+	}
+
+	return s
+}
+
+// Equal reports whether trades have equal values.
+// Timestamps are compared by time.Time.Equal.
+func (tr Trade) Equal(o Trade) bool {
+	return tr.t == o.t &&
+		tr.price == o.price &&
+		tr.count == o.count &&
+		tr.side == o.side &&
+		tr.Timestamp.Equal(o.Timestamp)
+}
+
+// tradeJSON is a JSON representation of Trade.
+type tradeJSON struct {
+	Ticker    string    `json:"ticker"`
+	Price     float64   `json:"price"`
+	Count     int       `json:"count"`
+	Side      string    `json:"side,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (tr Trade) MarshalJSON() ([]byte, error) {
+	return json.Marshal(tradeJSON{
+		Ticker:    string(tr.t),
+		Price:     tr.price,
+		Count:     tr.count,
+		Side:      tr.side.String(),
+		Timestamp: tr.Timestamp,
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+// Trade values are validated as by NewTrade,
+// unlike values of trades parsed from strings.
+func (tr *Trade) UnmarshalJSON(data []byte) error {
+	var v tradeJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	side, ok := parseSide([]byte(v.Side))
+	if !ok {
+		return ErrInvalidValue
+	}
+
+	t, err := NewTrade(v.Ticker, v.Price, v.Count, side, v.Timestamp)
+	if err != nil {
+		return err
+	}
+
+	*tr = t
+
+	return nil
+}
+
+// CompareTrades orders trades by timestamp and then by ticker.
+// Returns -1 if a is before b, 1 if a is after b and 0 otherwise.
+func CompareTrades(a, b Trade) int {
+	if c := compareTimes(a.Timestamp, b.Timestamp); c != 0 {
+		return c
+	}
+
+	return strings.Compare(string(a.t), string(b.t))
+}
+
+// compareTimes returns -1 if a is before b, 1 if a is after b and 0 otherwise.
+func compareTimes(a, b time.Time) int {
+	switch {
+	case a.Before(b):
+		return -1
+	case a.After(b):
+		return 1
+	default:
+		return 0
+	}
+}
+
 // MustTradeFromString parse a trade from a string.
 // Panics if string is invalid for parsing.
 func MustTradeFromString(s string) Trade {
@@ -38,38 +212,310 @@ func MustTradeFromString(s string) Trade {
 
 // TradeFromString parse Trade from string.
 func TradeFromString(s string) (Trade, error) {
-	values := strings.Split(strings.TrimSpace(s), ",")
-	if len(values) != tradeDataLen {
+	return parseTrade([]byte(s), nil)
+}
+
+// TradeFromBytes parse Trade from byte slice.
+// The slice is not retained, so it can be reused after the call.
+func TradeFromBytes(b []byte) (Trade, error) {
+	return parseTrade(b, nil)
+}
+
+// Parser parses trades from byte slices without allocations
+// for already seen tickers.
+// Parser is not safe for concurrent use.
+type Parser struct {
+	tickers map[string]ticker
+}
+
+// NewParser creates new Parser.
+func NewParser() *Parser {
+	return &Parser{
+		tickers: make(map[string]ticker),
+	}
+}
+
+// Parse parses Trade from byte slice.
+// The slice is not retained, so it can be reused after the call.
+func (p *Parser) Parse(b []byte) (Trade, error) {
+	return parseTrade(b, p.tickers)
+}
+
+// parseTrade parses Trade from line of "ticker,price,count,timestamp[,side]" format.
+// Values aren't validated as by NewTrade, so NaN, infinite or not positive prices,
+// not positive counts and zero timestamps are accepted as they always were.
+// If tickers is not nil, it is used to intern ticker values.
+func parseTrade(b []byte, tickers map[string]ticker) (Trade, error) {
+	var values [tradeWithSideLen][]byte
+
+	b = bytes.TrimSpace(b)
+
+	n, from := 0, 0
+
+	for i, c := range b {
+		if c != ',' {
+			continue
+		}
+
+		if n == tradeWithSideLen-1 {
+			return Trade{}, ErrInvalidValue
+		}
+
+		values[n] = b[from:i]
+		from = i + 1
+		n++
+	}
+
+	if n < tradeDataLen-1 {
+		return Trade{}, ErrInvalidValue
+	}
+
+	values[n] = b[from:]
+
+	// side is optional, but its field must not be empty.
+	if n == tradeWithSideLen-1 && len(values[n]) == 0 {
 		return Trade{}, ErrInvalidValue
 	}
 
-	t := values[0]
-	if len(t) == 0 {
+	if len(values[0]) == 0 {
 		return Trade{}, ErrInvalidTicker
 	}
 
-	price, err := strconv.ParseFloat(values[1], 64)
+	price, err := parseFloat(values[1])
 	if err != nil {
 		return Trade{}, ErrInvalidPrice
 	}
 
-	count, err := strconv.Atoi(values[2])
+	count, err := parseInt(values[2])
 	if err != nil {
 		return Trade{}, ErrInvalidCount
 	}
 
-	timestamp, err := time.Parse(
-		"2006-01-02 15:04:05.999999",
-		values[3],
-	)
+	timestamp, err := parseTimestamp(values[3])
 	if err != nil {
 		return Trade{}, ErrInvalidTime
 	}
 
+	side, ok := parseSide(values[4])
+	if !ok {
+		return Trade{}, ErrInvalidValue
+	}
+
 	return Trade{
-		t:         ticker(t),
+		t:         internTicker(values[0], tickers),
 		price:     price,
 		count:     count,
+		side:      side,
 		Timestamp: timestamp,
 	}, nil
 }
+
+// parseSide parses optional trade side: "B" or "BUY" for buy side,
+// "S" or "SELL" for sell side, case insensitive.
+// Empty value means unknown side.
+func parseSide(b []byte) (Side, bool) {
+	switch {
+	case len(b) == 0:
+		return SideUnknown, true
+	case bytes.EqualFold(b, []byte("B")) || bytes.EqualFold(b, []byte("BUY")):
+		return SideBuy, true
+	case bytes.EqualFold(b, []byte("S")) || bytes.EqualFold(b, []byte("SELL")):
+		return SideSell, true
+	default:
+		return SideUnknown, false
+	}
+}
+
+// internTicker returns ticker for provided bytes,
+// reusing previously seen value if tickers is not nil.
+func internTicker(b []byte, tickers map[string]ticker) ticker {
+	if tickers == nil {
+		return ticker(b)
+	}
+
+	// map lookup by string(b) conversion does not allocate.
+	if t, ok := tickers[string(b)]; ok {
+		return t
+	}
+
+	t := ticker(b)
+	tickers[string(t)] = t
+
+	return t
+}
+
+// float64pow10 contains powers of ten exactly representable as float64.
+var float64pow10 = [...]float64{
+	1e0, 1e1, 1e2, 1e3, 1e4, 1e5, 1e6, 1e7, 1e8, 1e9, 1e10,
+	1e11, 1e12, 1e13, 1e14, 1e15, 1e16, 1e17, 1e18, 1e19, 1e20, 1e21, 1e22,
+}
+
+// parseFloat parses plain decimal numbers like "-213.8" without allocations,
+// falls back to strconv.ParseFloat for every other format.
+func parseFloat(b []byte) (float64, error) {
+	const maxExactMantissa = 1 << 53
+
+	var (
+		mantissa uint64
+		digits   int
+		frac     = -1
+		neg      bool
+	)
+
+	s := b
+	if len(s) > 0 && (s[0] == '-' || s[0] == '+') {
+		neg = s[0] == '-'
+		s = s[1:]
+	}
+
+	for i, c := range s {
+		switch {
+		case c >= '0' && c <= '9':
+			mantissa = mantissa*10 + uint64(c-'0')
+			digits++
+		case c == '.' && frac < 0:
+			frac = len(s) - i - 1
+		default:
+			return strconv.ParseFloat(string(b), 64)
+		}
+
+		if mantissa >= maxExactMantissa {
+			return strconv.ParseFloat(string(b), 64)
+		}
+	}
+
+	if frac < 0 {
+		frac = 0
+	}
+
+	if digits == 0 || frac >= len(float64pow10) {
+		return strconv.ParseFloat(string(b), 64)
+	}
+
+	// both values are exact, so division is correctly rounded.
+	f := float64(mantissa) / float64pow10[frac]
+	if neg {
+		f = -f
+	}
+
+	return f, nil
+}
+
+// parseInt parses decimal integers without allocations,
+// falls back to strconv.Atoi for every other format.
+func parseInt(b []byte) (int, error) {
+	const maxFastDigits = 18
+
+	s := b
+	neg := false
+
+	if len(s) > 0 && (s[0] == '-' || s[0] == '+') {
+		neg = s[0] == '-'
+		s = s[1:]
+	}
+
+	if len(s) == 0 || len(s) > maxFastDigits {
+		return strconv.Atoi(string(b))
+	}
+
+	n := 0
+
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return strconv.Atoi(string(b))
+		}
+
+		n = n*10 + int(c-'0')
+	}
+
+	if neg {
+		n = -n
+	}
+
+	return n, nil
+}
+
+// parseTimestamp parses timestamp of "2006-01-02 15:04:05.999999" layout.
+// Fixed-width values are parsed without allocations,
+// every other value falls back to time.Parse.
+func parseTimestamp(b []byte) (time.Time, error) {
+	if t, ok := parseFixedTimestamp(b); ok {
+		return t, nil
+	}
+
+	return time.Parse(timeLayout, string(b))
+}
+
+// parseFixedTimestamp parses "YYYY-MM-DD hh:mm:ss[.f...]" timestamp
+// with up to nine fractional digits.
+// Reports false if value doesn't match the layout or is out of range.
+func parseFixedTimestamp(b []byte) (time.Time, bool) {
+	const (
+		dateTimeLen   = len("2006-01-02 15:04:05")
+		maxFracDigits = 9
+		hoursInDay    = 24
+		minutesInHour = 60
+	)
+
+	if len(b) < dateTimeLen ||
+		b[4] != '-' || b[7] != '-' || b[10] != ' ' || b[13] != ':' || b[16] != ':' {
+		return time.Time{}, false
+	}
+
+	year, ok1 := digitsValue(b[0:4])
+	month, ok2 := digitsValue(b[5:7])
+	day, ok3 := digitsValue(b[8:10])
+	hour, ok4 := digitsValue(b[11:13])
+	minute, ok5 := digitsValue(b[14:16])
+	sec, ok6 := digitsValue(b[17:19])
+
+	if !(ok1 && ok2 && ok3 && ok4 && ok5 && ok6) {
+		return time.Time{}, false
+	}
+
+	if hour >= hoursInDay || minute >= minutesInHour || sec >= minutesInHour {
+		return time.Time{}, false
+	}
+
+	nsec := 0
+
+	if frac := b[dateTimeLen:]; len(frac) > 0 {
+		if frac[0] != '.' || len(frac) == 1 || len(frac) > maxFracDigits+1 {
+			return time.Time{}, false
+		}
+
+		v, ok := digitsValue(frac[1:])
+		if !ok {
+			return time.Time{}, false
+		}
+
+		nsec = v
+		for i := len(frac) - 1; i < maxFracDigits; i++ {
+			nsec *= 10
+		}
+	}
+
+	t := time.Date(year, time.Month(month), day, hour, minute, sec, nsec, time.UTC)
+	if t.Day() != day || int(t.Month()) != month {
+		// day is out of month range, time.Date normalized it.
+		return time.Time{}, false
+	}
+
+	return t, true
+}
+
+// digitsValue returns value of decimal digits.
+// Reports false if b contains non-digit bytes.
+func digitsValue(b []byte) (int, bool) {
+	n := 0
+
+	for _, c := range b {
+		if c < '0' || c > '9' {
+			return 0, false
+		}
+
+		n = n*10 + int(c-'0')
+	}
+
+	return n, true
+}
@@ -1,6 +1,7 @@
 package candles
 
 import (
+	"strconv"
 	"testing"
 	"time"
 
@@ -38,12 +39,48 @@ func TestInternalTradeFromString(t *testing.T) {
 			},
 			wantErr: nil,
 		},
+		{
+			name: "success, buy side",
+			args: args{s: "TICKER,213.8,10,2019-01-30 06:59:45.000249,B"},
+			want: Trade{
+				t:         ticker("TICKER"),
+				price:     213.8,
+				count:     10,
+				side:      SideBuy,
+				Timestamp: mustParseTime("2019-01-30 06:59:45.000249"),
+			},
+			wantErr: nil,
+		},
+		{
+			name: "success, sell side",
+			args: args{s: "TICKER,213.8,10,2019-01-30 06:59:45.000249,sell"},
+			want: Trade{
+				t:         ticker("TICKER"),
+				price:     213.8,
+				count:     10,
+				side:      SideSell,
+				Timestamp: mustParseTime("2019-01-30 06:59:45.000249"),
+			},
+			wantErr: nil,
+		},
+		{
+			name:    "empty side",
+			args:    args{s: "TICKER,213.8,10,2019-01-30 06:59:45.000249,"},
+			want:    Trade{},
+			wantErr: ErrInvalidValue,
+		},
 		{
 			name:    "num of elements exceeded",
 			args:    args{s: "TICKER,213.8,10,2019-01-30 06:59:45.000249,new-info"},
 			want:    Trade{},
 			wantErr: ErrInvalidValue,
 		},
+		{
+			name:    "num of elements exceeded with side",
+			args:    args{s: "TICKER,213.8,10,2019-01-30 06:59:45.000249,B,new-info"},
+			want:    Trade{},
+			wantErr: ErrInvalidValue,
+		},
 		{
 			name:    "invalid ticker",
 			args:    args{s: ",213.8,10,2019-01-30 06:59:45.000249"},
@@ -129,3 +166,72 @@ func TestInternalMustTradeFromString(t *testing.T) {
 		})
 	}
 }
+
+func TestInternal_parseTimestamp(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+	}{
+		{name: "microseconds", s: "2019-01-30 06:59:45.000249"},
+		{name: "nanoseconds", s: "2019-01-30 06:59:45.123456789"},
+		{name: "single fractional digit", s: "2019-01-30 06:59:45.5"},
+		{name: "no fractional part", s: "2019-01-30 06:59:45"},
+		{name: "leap day", s: "2020-02-29 23:59:59.999999"},
+		{name: "day out of range", s: "2019-02-29 10:00:00"},
+		{name: "month out of range", s: "2019-13-01 10:00:00"},
+		{name: "hour out of range", s: "2019-01-30 24:00:00"},
+		{name: "more than nine fractional digits", s: "2019-01-30 06:59:45.1234567891"},
+		{name: "empty fractional part", s: "2019-01-30 06:59:45."},
+		{name: "not a timestamp", s: "three-o-clock"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			want, wantErr := time.Parse(timeLayout, test.s)
+			got, err := parseTimestamp([]byte(test.s))
+			assert.Equal(t, want, got)
+			assert.Equal(t, wantErr != nil, err != nil)
+		})
+	}
+}
+
+func TestInternal_parseFloat(t *testing.T) {
+	tests := []string{
+		"213.8", "-213.8", "+1", "0.1", ".5", "1.", "0", "-0",
+		"123456789.123456789", "9007199254740993", "1e5", "1.5e-3", "inf", ".", "", "1.2.3", "abc",
+	}
+
+	for _, s := range tests {
+		t.Run(s, func(t *testing.T) {
+			want, wantErr := strconv.ParseFloat(s, 64)
+			got, err := parseFloat([]byte(s))
+			assert.Equal(t, want, got)
+			assert.Equal(t, wantErr != nil, err != nil)
+		})
+	}
+}
+
+func TestInternal_parseInt(t *testing.T) {
+	tests := []string{
+		"10", "-10", "+10", "0", "99999999999999999999", "five", "", "-", "1.5",
+	}
+
+	for _, s := range tests {
+		t.Run(s, func(t *testing.T) {
+			want, wantErr := strconv.Atoi(s)
+			got, err := parseInt([]byte(s))
+			assert.Equal(t, want, got)
+			assert.Equal(t, wantErr != nil, err != nil)
+		})
+	}
+}
+
+func BenchmarkInternal_parseTimestamp(b *testing.B) {
+	ts := []byte("2019-01-30 06:59:45.000249")
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		_, _ = parseTimestamp(ts)
+	}
+}
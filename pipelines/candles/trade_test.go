@@ -1,6 +1,8 @@
 package candles_test
 
 import (
+	"encoding/json"
+	"math"
 	"testing"
 	"time"
 
@@ -65,6 +67,27 @@ func TestTradeFromString(t *testing.T) {
 			wantTimeStamp: time.Time{},
 			wantErr:       candles.ErrInvalidTime,
 		},
+		{
+			name:          "empty side",
+			args:          args{s: "TICKER,213.8,10,2019-01-30 06:59:45.000249,"},
+			wantTimeStamp: time.Time{},
+			wantErr:       candles.ErrInvalidValue,
+		},
+		{
+			name:          "NaN price",
+			args:          args{s: "TICKER,NaN,10,2019-01-30 06:59:45.000249"},
+			wantTimeStamp: mustParseTime("2019-01-30 06:59:45.000249"),
+		},
+		{
+			name:          "infinite price",
+			args:          args{s: "TICKER,+Inf,10,2019-01-30 06:59:45.000249"},
+			wantTimeStamp: mustParseTime("2019-01-30 06:59:45.000249"),
+		},
+		{
+			name:          "zero timestamp",
+			args:          args{s: "TICKER,213.8,10,0001-01-01 00:00:00"},
+			wantTimeStamp: time.Time{},
+		},
 	}
 
 	for _, test := range tests {
@@ -121,3 +144,220 @@ func TestMustTradeFromString(t *testing.T) {
 		})
 	}
 }
+
+func TestParser_Parse(t *testing.T) {
+	type args struct {
+		lines []string
+	}
+
+	tests := []struct {
+		name    string
+		args    args
+		want    []string
+		wantErr error
+	}{
+		{
+			name: "success, same ticker multiple times",
+			args: args{lines: []string{
+				"TICKER,213.8,10,2019-01-30 06:59:45.000249",
+				"TICKER,100,20,2019-01-30 07:00:00",
+			}},
+			want: []string{
+				"TICKER,2019-01-30T06:59:45Z,213.800000,213.800000,213.800000,213.800000",
+				"TICKER,2019-01-30T07:00:00Z,100.000000,100.000000,100.000000,100.000000",
+			},
+		},
+		{
+			name:    "invalid ticker",
+			args:    args{lines: []string{",213.8,10,2019-01-30 06:59:45.000249"}},
+			wantErr: candles.ErrInvalidTicker,
+		},
+		{
+			name:    "num of elements exceeded",
+			args:    args{lines: []string{"TICKER,213.8,10,2019-01-30 06:59:45.000249,new-info"}},
+			wantErr: candles.ErrInvalidValue,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p := candles.NewParser()
+			got := make([]string, 0, len(test.args.lines))
+
+			for _, l := range test.args.lines {
+				tr, err := p.Parse([]byte(l))
+				if err != nil {
+					assert.Equal(t, test.wantErr, err)
+					return
+				}
+
+				got = append(got, candles.New(tr, tr.Timestamp.Truncate(time.Second)).String())
+			}
+
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
+
+func TestTradeFromBytes(t *testing.T) {
+	line := []byte("TICKER,213.8,10,2019-01-30 06:59:45.000249")
+
+	got, err := candles.TradeFromBytes(line)
+	assert.NoError(t, err)
+
+	// the slice can be reused after parsing.
+	copy(line, "OTHER_")
+
+	want := candles.MustTradeFromString("TICKER,213.8,10,2019-01-30 06:59:45.000249")
+	assert.Equal(t, want, got)
+}
+
+func BenchmarkTradeFromString(b *testing.B) {
+	const line = "TICKER,213.8,10,2019-01-30 06:59:45.000249"
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		_, _ = candles.TradeFromString(line)
+	}
+}
+
+func BenchmarkTimeParse(b *testing.B) {
+	const ts = "2019-01-30 06:59:45.000249"
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		_, _ = time.Parse("2006-01-02 15:04:05.999999", ts)
+	}
+}
+
+func BenchmarkParser_Parse(b *testing.B) {
+	line := []byte("TICKER,213.8,10,2019-01-30 06:59:45.000249")
+	p := candles.NewParser()
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		_, _ = p.Parse(line)
+	}
+}
+
+func TestNewTrade(t *testing.T) {
+	ts := time.Date(2019, 1, 30, 10, 0, 1, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		ticker  string
+		price   float64
+		count   int
+		side    candles.Side
+		ts      time.Time
+		wantErr error
+	}{
+		{name: "success", ticker: "TICKER", price: 213.8, count: 10, side: candles.SideBuy, ts: ts},
+		{name: "empty ticker", ticker: "", price: 213.8, count: 10, ts: ts, wantErr: candles.ErrInvalidTicker},
+		{name: "ticker with comma", ticker: "TI,CKER", price: 213.8, count: 10, ts: ts, wantErr: candles.ErrInvalidTicker},
+		{name: "NaN price", ticker: "TICKER", price: math.NaN(), count: 10, ts: ts, wantErr: candles.ErrInvalidPrice},
+		{name: "infinite price", ticker: "TICKER", price: math.Inf(1), count: 10, ts: ts, wantErr: candles.ErrInvalidPrice},
+		{name: "zero price", ticker: "TICKER", price: 0, count: 10, ts: ts, wantErr: candles.ErrInvalidPrice},
+		{name: "negative price", ticker: "TICKER", price: -1, count: 10, ts: ts, wantErr: candles.ErrInvalidPrice},
+		{name: "zero count", ticker: "TICKER", price: 213.8, count: 0, ts: ts, wantErr: candles.ErrInvalidCount},
+		{name: "negative count", ticker: "TICKER", price: 213.8, count: -5, ts: ts, wantErr: candles.ErrInvalidCount},
+		{name: "zero timestamp", ticker: "TICKER", price: 213.8, count: 10, wantErr: candles.ErrInvalidTime},
+		{name: "invalid side", ticker: "TICKER", price: 213.8, count: 10, side: 3, ts: ts, wantErr: candles.ErrInvalidValue},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tr, err := candles.NewTrade(test.ticker, test.price, test.count, test.side, test.ts)
+			assert.Equal(t, test.wantErr, err)
+
+			if err != nil {
+				return
+			}
+
+			assert.Equal(t, test.ticker, tr.Ticker())
+			assert.Equal(t, test.price, tr.Price())
+			assert.Equal(t, test.count, tr.Count())
+			assert.Equal(t, test.side, tr.Side())
+			assert.Equal(t, test.ts, tr.Timestamp)
+		})
+	}
+}
+
+func TestTrade_String(t *testing.T) {
+	for _, s := range []string{
+		"TICKER,213.8,10,2019-01-30 10:00:01.000249",
+		"TICKER,100,1,2019-01-30 10:00:01,S",
+	} {
+		tr := candles.MustTradeFromString(s)
+		assert.Equal(t, s, tr.String())
+		assert.True(t, tr.Equal(candles.MustTradeFromString(tr.String())))
+	}
+}
+
+func TestTrade_JSON(t *testing.T) {
+	tr := candles.MustTradeFromString("TICKER,213.8,10,2019-01-30 10:00:01.000249,B")
+
+	data, err := json.Marshal(tr)
+	assert.NoError(t, err)
+	assert.Equal(t,
+		`{"ticker":"TICKER","price":213.8,"count":10,"side":"B","timestamp":"2019-01-30T10:00:01.000249Z"}`,
+		string(data),
+	)
+
+	var got candles.Trade
+	assert.NoError(t, json.Unmarshal(data, &got))
+	assert.True(t, tr.Equal(got))
+
+	assert.Equal(t, candles.ErrInvalidTime,
+		json.Unmarshal([]byte(`{"ticker":"TICKER","price":1,"count":1}`), &got))
+	assert.Equal(t, candles.ErrInvalidValue,
+		json.Unmarshal([]byte(`{"ticker":"TICKER","price":1,"count":1,"side":"X","timestamp":"2019-01-30T10:00:01Z"}`), &got))
+}
+
+func TestTrade_JSONRoundTrip(t *testing.T) {
+	for _, s := range []string{
+		"TICKER,213.8,10,2019-01-30 10:00:01.000249,B",
+		"TICKER,100,1,2019-01-30 10:00:01,S",
+	} {
+		tr, err := candles.TradeFromString(s)
+		assert.NoError(t, err)
+
+		data, err := json.Marshal(tr)
+		assert.NoError(t, err)
+
+		var got candles.Trade
+		assert.NoError(t, json.Unmarshal(data, &got))
+		assert.Equal(t, s, got.String())
+	}
+
+	// parser accepts trades with not positive values, JSON is validated as by NewTrade.
+	for s, want := range map[string]error{
+		"TICKER,0,1,2019-01-30 10:00:01":    candles.ErrInvalidPrice,
+		"TICKER,-1,1,2019-01-30 10:00:01,S": candles.ErrInvalidPrice,
+		"TICKER,1,0,2019-01-30 10:00:01":    candles.ErrInvalidCount,
+	} {
+		tr, err := candles.TradeFromString(s)
+		assert.NoError(t, err)
+
+		data, err := json.Marshal(tr)
+		assert.NoError(t, err)
+
+		var got candles.Trade
+		assert.Equal(t, want, json.Unmarshal(data, &got))
+	}
+}
+
+func TestCompareTrades(t *testing.T) {
+	a := candles.MustTradeFromString("AAPL,100,1,2019-01-30 10:00:01")
+	b := candles.MustTradeFromString("MSFT,100,1,2019-01-30 10:00:01")
+	c := candles.MustTradeFromString("AAPL,100,1,2019-01-30 10:00:02")
+
+	assert.Equal(t, -1, candles.CompareTrades(a, b))
+	assert.Equal(t, 1, candles.CompareTrades(b, a))
+	assert.Equal(t, -1, candles.CompareTrades(b, c))
+	assert.Equal(t, 0, candles.CompareTrades(a, a))
+	assert.False(t, a.Equal(b))
+}
@@ -0,0 +1,265 @@
+package pipelines
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/candles/pipelines/candles"
+)
+
+var (
+	errResumeUnsupported     = errors.New("input or output doesn't support resume")
+	errCheckpointUnsupported = errors.New("output doesn't support checkpoints")
+	errNotInCheckpoint       = errors.New("pipeline is not present in checkpoint")
+)
+
+// resumableReader is implemented by inputs,
+// which can start reading from provided offset.
+type resumableReader interface {
+	SetOffset(offset int64) error
+}
+
+// committer is implemented by inputs, which can be told
+// that data before offset is durably processed and doesn't have to be read again.
+type committer interface {
+	Commit(offset int64) error
+}
+
+// syncer is implemented by outputs, which can commit written data
+// and report its size.
+type syncer interface {
+	Sync() (int64, error)
+}
+
+// canSync reports whether output supports checkpoints.
+func canSync(s Sink) bool {
+	if ts, ok := s.(*TextSink); ok {
+		_, ok = ts.fw.(syncer)
+		return ok
+	}
+
+	_, ok := s.(syncer)
+
+	return ok
+}
+
+// resumableBuilder is implemented by writers builders,
+// which can continue writing to existing outputs.
+type resumableBuilder interface {
+	Open(filepath string, size int64) (Sink, error)
+}
+
+// checkpoint describes pipelines state persisted to resume interrupted run.
+type checkpoint struct {
+	// Offset is the input offset of the first not processed trade.
+	Offset  int64                  `json:"offset"`
+	Workers map[string]workerState `json:"workers"`
+	// Outputs contains sizes of data written to outputs by their names.
+	Outputs map[string]int64 `json:"outputs"`
+	// Rules contains state of stateful validation rules by their names.
+	Rules map[string]json.RawMessage `json:"rules,omitempty"`
+}
+
+// workerState describes state of a single pipeline.
+type workerState struct {
+	IntervalStart time.Time        `json:"interval_start"`
+	IntervalEnd   time.Time        `json:"interval_end"`
+	Candles       []candles.Candle `json:"candles"`
+	// Bars contains state of bars closed by trading activity or price moves.
+	Bars json.RawMessage `json:"bars,omitempty"`
+	// HeikinAshi contains state of Heikin-Ashi transform.
+	HeikinAshi json.RawMessage `json:"heikin_ashi,omitempty"`
+	// Indicators contains state of indicators.
+	Indicators json.RawMessage `json:"indicators,omitempty"`
+}
+
+// EnableCheckpoints enables periodical persisting of pipelines state to path.
+// Must be called before Init.
+func (ps *Pipelines) EnableCheckpoints(path string, every time.Duration) {
+	ps.cpPath = path
+	ps.cpEvery = every
+}
+
+// Resume loads checkpoint from path and makes pipelines continue from it.
+// Must be called after SetRules and before Add.
+func (ps *Pipelines) Resume(path string) error {
+	cp, err := loadCheckpoint(path)
+	if err != nil {
+		return err
+	}
+
+	rr, ok := ps.r.(resumableReader)
+	if !ok {
+		return errResumeUnsupported
+	}
+
+	if _, ok = ps.wb.(resumableBuilder); !ok {
+		return errResumeUnsupported
+	}
+
+	// validation rules see trades before the offset as in uninterrupted run.
+	if ps.validator != nil {
+		if err = ps.validator.restoreState(cp.Rules); err != nil {
+			return err
+		}
+	}
+
+	if err = rr.SetOffset(cp.Offset); err != nil {
+		return err
+	}
+
+	ps.resume = &cp
+	ps.l.Infof("Resuming from checkpoint at offset %d", cp.Offset)
+
+	return nil
+}
+
+// checkpoint persists state of all pipelines, consistent with provided input offset.
+// Has to be called from the stage two goroutine between trades dispatching under lock,
+// so workers have already received all trades before the offset.
+func (ps *Pipelines) checkpoint(offset int64) error {
+	cp := checkpoint{
+		Offset:  offset,
+		Workers: make(map[string]workerState, len(ps.workers)),
+		Outputs: make(map[string]int64, len(ps.writers)),
+	}
+
+	// workers process trades and control functions in order,
+	// so state includes all dispatched trades.
+	for _, w := range ps.workers {
+		w := w
+
+		var (
+			st  workerState
+			err error
+		)
+
+		w.do(func() {
+			st, err = w.state()
+		})
+
+		if err != nil {
+			return err
+		}
+
+		cp.Workers[w.name] = st
+	}
+
+	// rules are applied in this goroutine, so state includes all dispatched trades.
+	if ps.validator != nil {
+		rules, err := ps.validator.state()
+		if err != nil {
+			return err
+		}
+
+		cp.Rules = rules
+	}
+
+	// same for writers: all data flushed before workers state taken is queued for sinks,
+	// and sinks write queued data before sync.
+	for _, w := range ps.writers {
+		w := w
+
+		var err error
+
+		w.do(func() {
+			err = w.sync(cp.Outputs)
+		})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	// lines rejected before the offset are not read again on resume.
+	if ps.quarantine != nil {
+		if err := ps.quarantine.sync(); err != nil {
+			return err
+		}
+	}
+
+	if err := saveCheckpoint(ps.cpPath, cp); err != nil {
+		return err
+	}
+
+	if c, ok := ps.r.(committer); ok {
+		return c.Commit(offset)
+	}
+
+	return nil
+}
+
+// sync commits data written to all sinks of writer
+// and puts sizes of written data to sizes by checkpoint names of outputs.
+// Has to be called inside writer goroutine.
+func (w *Writer) sync(sizes map[string]int64) error {
+	for _, sw := range w.sinks {
+		sw := sw
+
+		var (
+			size int64
+			err  error
+		)
+
+		sw.do(func() {
+			size, err = sw.s.(syncer).Sync()
+		})
+
+		if err != nil {
+			return err
+		}
+
+		sizes[outputKey(sw.name, w.name)] = size
+	}
+
+	return nil
+}
+
+// loadCheckpoint reads checkpoint from file.
+func loadCheckpoint(path string) (checkpoint, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return checkpoint{}, err
+	}
+
+	var cp checkpoint
+	if err = json.Unmarshal(data, &cp); err != nil {
+		return checkpoint{}, err
+	}
+
+	return cp, nil
+}
+
+// saveCheckpoint atomically replaces checkpoint file.
+func saveCheckpoint(path string, cp checkpoint) error {
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+
+	if _, err = f.Write(data); err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	if err = f.Sync(); err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	if err = f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
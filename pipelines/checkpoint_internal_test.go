@@ -0,0 +1,165 @@
+package pipelines
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/candles/files"
+	"github.com/candles/pipelines/candles"
+)
+
+// syncWriterMock is a FileWriter mock, which reports written data size on sync.
+type syncWriterMock struct {
+	written []string
+	size    int64
+}
+
+func (w *syncWriterMock) WriteString(s string) error {
+	w.written = append(w.written, s)
+	w.size += int64(len(s))
+
+	return nil
+}
+
+func (w *syncWriterMock) Close() {}
+
+func (w *syncWriterMock) Sync() (int64, error) {
+	return w.size, nil
+}
+
+func TestCheckpoint_Internal_saveLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkpoint")
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "checkpoint.json")
+	iStart := mustParseTime("2019-01-30 11:00:00.000000")
+
+	want := checkpoint{
+		Offset: 100,
+		Workers: map[string]workerState{
+			"candle_5min": {
+				IntervalStart: iStart,
+				IntervalEnd:   iStart.Add(time.Minute * 5),
+				Candles: []candles.Candle{
+					*candles.New(candles.MustTradeFromString("TICKER,213.8,10,2019-01-30 11:00:45.000249"), iStart),
+				},
+			},
+		},
+		Outputs: map[string]int64{"candle_5min": 200},
+	}
+
+	assert.NoError(t, saveCheckpoint(path, want))
+
+	got, err := loadCheckpoint(path)
+	assert.NoError(t, err)
+	assert.Equal(t, want, got)
+
+	_, err = loadCheckpoint(filepath.Join(dir, "not-exists.json"))
+	assert.Error(t, err)
+}
+
+func TestPipelines_Internal_checkpoint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkpoint")
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "checkpoint.json")
+	iStart := mustParseTime("2019-01-30 11:00:00.000000")
+
+	ps := New(readerMock{}, WriterBuilder{}, logrus.New())
+	ps.EnableCheckpoints(path, 0)
+
+	w := NewWorker(5)
+	w.name = "candle_5min"
+	fw := &syncWriterMock{}
+	ps.workers = append(ps.workers, w)
+	ps.writers = append(ps.writers, NewWriter(NewTextSink(fw), w.out, ps.l))
+	ps.writers[0].name = w.name
+
+	go w.start()
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+
+	go ps.writers[0].startWriting(wg)
+
+	for _, s := range []string{
+		"TICKER,100,10,2019-01-30 11:00:01",
+		"TICKER,200,10,2019-01-30 11:06:00",
+	} {
+		w.in <- candles.MustTradeFromString(s)
+	}
+
+	assert.NoError(t, ps.checkpoint(42))
+
+	close(w.in)
+	wg.Wait()
+
+	got, err := loadCheckpoint(path)
+	assert.NoError(t, err)
+
+	want := checkpoint{
+		Offset: 42,
+		Workers: map[string]workerState{
+			"candle_5min": {
+				IntervalStart: iStart.Add(time.Minute * 5),
+				IntervalEnd:   iStart.Add(time.Minute * 10),
+				Candles: []candles.Candle{
+					*candles.New(candles.MustTradeFromString("TICKER,200,10,2019-01-30 11:06:00"), iStart.Add(time.Minute*5)),
+				},
+			},
+		},
+		Outputs: map[string]int64{"candle_5min": int64(len(fw.written[0]))},
+	}
+	assert.Equal(t, want, got)
+}
+
+func TestPipelines_Internal_Resume(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkpoint")
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "checkpoint.json")
+	assert.NoError(t, saveCheckpoint(path, checkpoint{Offset: 10}))
+
+	tradesPath := filepath.Join(dir, "trades.csv")
+	assert.NoError(t, ioutil.WriteFile(tradesPath, []byte("TICKER,100,10,2019-01-30 11:00:01\n"), 0600))
+
+	r, err := files.NewReader(tradesPath, logrus.New())
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		ps      *Pipelines
+		wantErr bool
+	}{
+		{
+			name: "success",
+			ps:   New(r, WriterBuilder{}, logrus.New()),
+		},
+		{
+			name:    "reader doesn't support resume",
+			ps:      New(readerMock{}, WriterBuilder{}, logrus.New()),
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.ps.Resume(path)
+			assert.Equal(t, test.wantErr, err != nil)
+			assert.Equal(t, test.wantErr, test.ps.resume == nil)
+		})
+	}
+}
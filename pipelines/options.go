@@ -0,0 +1,152 @@
+package pipelines
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/candles/pipelines/candles"
+)
+
+// Option configures pipeline added to aggregator.
+type Option func(w *Worker)
+
+// WithOrderFlow adds buy volume, sell volume and delta columns to candles output.
+func WithOrderFlow() Option {
+	return func(w *Worker) {
+		w.columns = append(w.columns, (*candles.Candle).OrderFlowString)
+	}
+}
+
+// WithTradeTimes adds times of open, high, low and close trades columns to candles output.
+// To be appended to, output has to have these columns after order flow ones.
+func WithTradeTimes() Option {
+	return func(w *Worker) {
+		w.columns = append(w.columns, (*candles.Candle).TradeTimesString)
+	}
+}
+
+// WithPartialCandles makes pipeline periodically write not closed candles
+// to a separate output named with "partial_" prefix,
+// so candles output contains closed candles only.
+// Partial candles are not sent to Heikin-Ashi output.
+func WithPartialCandles(every time.Duration) Option {
+	return func(w *Worker) {
+		w.partialEvery = every
+	}
+}
+
+// WithCandlesHandler makes pipeline call h with output name and candles after they are output,
+// including Heikin-Ashi and not closed candles of outputs enabled by options.
+// h is called from pipeline goroutine, so it must not block,
+// candles must not be modified or retained after the call.
+func WithCandlesHandler(h func(name string, cs []candles.Candle)) Option {
+	return func(w *Worker) {
+		w.handler = h
+	}
+}
+
+// WithHeikinAshi makes pipeline write Heikin-Ashi candles
+// to a separate output named with "ha_" prefix.
+func WithHeikinAshi() Option {
+	return func(w *Worker) {
+		w.ha = candles.NewHeikinAshi()
+	}
+}
+
+// WithIndicators adds columns of provided technical indicators to candles output.
+// Indicators are computed from closed candles of each ticker.
+func WithIndicators(specs ...candles.IndicatorSpec) Option {
+	return func(w *Worker) {
+		w.indSpecs = append(w.indSpecs, specs...)
+	}
+}
+
+// WithBars makes pipeline build bars of provided kind instead of time candles.
+// Pipeline interval is used as bars size, unless it is set by WithBarSize.
+// Bars closed by price moves are output with their start and end times.
+func WithBars(kind candles.BarKind) Option {
+	return func(w *Worker) {
+		w.barKind = kind
+	}
+}
+
+// WithBarSize sets bars size of pipeline, overriding its interval.
+func WithBarSize(size float64) Option {
+	return func(w *Worker) {
+		w.barSize = size
+	}
+}
+
+// WithTickerBarSize sets bars size of pipeline for single ticker.
+func WithTickerBarSize(ticker string, size float64) Option {
+	return func(w *Worker) {
+		if w.barSizes == nil {
+			w.barSizes = make(map[string]float64)
+		}
+
+		w.barSizes[ticker] = size
+	}
+}
+
+// applyHeikinAshi creates Heikin-Ashi output with provided buffer size.
+// Does nothing if pipeline has no Heikin-Ashi output.
+func applyHeikinAshi(w *Worker, buffer int) {
+	if w.ha == nil {
+		return
+	}
+
+	w.haOut = make(chan Batch, buffer)
+}
+
+// applyPartialCandles creates partial candles output with provided buffer size.
+// Does nothing if pipeline has no partial candles output.
+func applyPartialCandles(w *Worker, buffer int) {
+	if w.partialEvery <= 0 {
+		return
+	}
+
+	w.partialOut = make(chan Batch, buffer)
+}
+
+// applyBars creates bars builder configured by options.
+// Does nothing for time candles pipelines.
+func applyBars(w *Worker) {
+	if w.barKind == 0 {
+		return
+	}
+
+	if w.barSize == 0 {
+		w.barSize = float64(w.interval)
+	}
+
+	w.bars = candles.NewBars(w.barKind, w.barSize)
+	for t, size := range w.barSizes {
+		w.bars.SetSize(t, size)
+	}
+
+	w.name = fmt.Sprintf("%s_%s", w.barKind, strconv.FormatFloat(w.barSize, 'f', -1, 64))
+
+	if w.barKind.PriceDriven() {
+		w.columns = append([]func(c *candles.Candle) string{(*candles.Candle).TimesString}, w.columns...)
+	}
+}
+
+// applyIndicators creates indicators configured by options.
+// Does nothing if pipeline has no indicators.
+func applyIndicators(w *Worker) error {
+	if len(w.indSpecs) == 0 {
+		return nil
+	}
+
+	ind, err := candles.NewIndicators(w.indSpecs...)
+	if err != nil {
+		return err
+	}
+
+	// VWAP is reset at the start of pipeline session, not at midnight.
+	ind.SetSessionStart(w.session.start)
+	w.ind = ind
+
+	return nil
+}
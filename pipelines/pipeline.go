@@ -3,31 +3,40 @@ package pipelines
 import (
 	"errors"
 	"fmt"
+	"runtime"
 	"sync"
 	"time"
 
 	"github.com/sirupsen/logrus"
 
+	"github.com/candles/files"
 	"github.com/candles/pipelines/candles"
 )
 
-var errIntervalAlreadyExists = errors.New("pipeline with provided interval already exists")
-
 var (
-	endHours   = 3
-	startHours = 10
+	errIntervalAlreadyExists = errors.New("pipeline with provided interval already exists")
+	errPipelineNotFound      = errors.New("pipeline not found")
+	errPipelinesStopped      = errors.New("pipelines are stopped")
 )
 
 type fileReader interface {
-	C() chan string
+	C() chan files.Batch
 	StartChan() chan struct{}
 	Init()
 }
 
 type WritersBuilder interface {
-	New(filepath string) (FileWriter, error)
+	New(filepath string) (Sink, error)
+}
+
+// Sink writes candles batches of pipeline output.
+// Batches are shared by all sinks of output, so they must not be modified.
+type Sink interface {
+	Write(b Batch) error
+	Close()
 }
 
+// FileWriter writes text data, it is used by TextSink.
 type FileWriter interface {
 	WriteString(string) error
 	Close()
@@ -42,8 +51,33 @@ type Pipelines struct {
 	r  fileReader
 	wb WritersBuilder
 
+	// mu guards workers and writers, as pipelines can be added and removed after Init.
+	mu      sync.RWMutex
 	workers []*Worker
 	writers []*Writer
+	sinks   []extraSink
+	parsers int
+	// started reports whether Init is called,
+	// stopped reports whether all trades are dispatched.
+	started bool
+	stopped bool
+	// wg waits for writers and for dispatching of trades.
+	wg sync.WaitGroup
+
+	// sinkOpts are options of the main sink.
+	sinkOpts  sinkOptions
+	inBuffer  int
+	outBuffer int
+
+	cpPath  string
+	cpEvery time.Duration
+	resume  *checkpoint
+	append  bool
+
+	quarantine *quarantine
+	validator  *validator
+	// session is the working range of trades, it is shared by all pipelines.
+	session session
 
 	l *logrus.Logger
 }
@@ -59,47 +93,321 @@ func New(fr fileReader, wb WritersBuilder, l *logrus.Logger) *Pipelines {
 		wb:      wb,
 		workers: make([]*Worker, 0, 3),
 		writers: make([]*Writer, 0, 3),
+		parsers: runtime.NumCPU(),
+		session: defaultSession,
 		l:       l,
+
+		sinkOpts: newSinkOptions(),
 	}
 	ps.l.Info("Pipelines created")
 
 	return ps
 }
 
-// Add adds new pipeline with provided time interval to aggregator.
-func (ps *Pipelines) Add(interval int) error {
-	for i := range ps.workers {
-		if ps.workers[i].interval == interval {
-			return errIntervalAlreadyExists
-		}
-	}
+// IntervalOutput returns the name of the output of time candles pipeline with provided interval.
+func IntervalOutput(interval int) string {
+	return fmt.Sprintf("candle_%dmin", interval)
+}
 
+// Add adds new pipeline with provided time interval and options to aggregator.
+// For bars closed by trading activity interval is the bars size.
+// Pipeline added after Init starts right away, skipping trades
+// until the start of the next interval.
+func (ps *Pipelines) Add(interval int, opts ...Option) error {
 	worker := NewWorker(interval)
-	fileName := fmt.Sprintf("candle_%dmin", interval)
+	worker.name = IntervalOutput(interval)
+	worker.in = make(chan candles.Trade, ps.inBuffer)
+	worker.out = make(chan Batch, ps.outBuffer)
+	worker.session = ps.session
 
-	fw, err := ps.wb.New(fileName)
-	if err != nil {
+	for _, opt := range opts {
+		opt(worker)
+	}
+
+	applyHeikinAshi(worker, ps.outBuffer)
+	applyPartialCandles(worker, ps.outBuffer)
+	applyBars(worker)
+
+	if err := applyIndicators(worker); err != nil {
+		return err
+	}
+
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	if ps.stopped {
+		return errPipelinesStopped
+	}
+
+	if ps.workerIndex(worker.name) >= 0 {
+		return errIntervalAlreadyExists
+	}
+
+	if err := ps.restoreWorker(worker); err != nil {
 		return err
 	}
 
+	writers := make([]*Writer, 0, 3)
+
+	for _, o := range worker.outputs() {
+		wr, err := ps.newWriter(worker, o)
+		if err != nil {
+			for _, wr := range writers {
+				wr.closeSinks()
+			}
+
+			return err
+		}
+
+		writers = append(writers, wr)
+	}
+
+	if ps.started {
+		worker.alignStart = true
+		ps.startPipeline(worker, writers)
+	}
+
 	ps.workers = append(ps.workers, worker)
-	ps.writers = append(ps.writers, NewWriter(fw, worker.out, ps.l))
+	ps.writers = append(ps.writers, writers...)
 
-	ps.l.Infof("Pipeline with interval %d added", interval)
+	ps.l.Infof("Pipeline %s added", worker.name)
 
 	return nil
 }
 
+// Remove removes pipeline with provided name from aggregator.
+// Pipeline removed after Init outputs its not closed candles,
+// Remove returns after its outputs are written and closed.
+func (ps *Pipelines) Remove(name string) error {
+	ps.mu.Lock()
+
+	i := ps.workerIndex(name)
+
+	switch {
+	case ps.stopped:
+		ps.mu.Unlock()
+		return errPipelinesStopped
+	case i < 0:
+		ps.mu.Unlock()
+		return errPipelineNotFound
+	}
+
+	w := ps.workers[i]
+	ps.workers = append(ps.workers[:i], ps.workers[i+1:]...)
+
+	names := make(map[string]bool, 2)
+	for _, o := range w.outputs() {
+		names[o.name] = true
+	}
+
+	writers := ps.writers[:0]
+	removed := make([]*Writer, 0, len(names))
+
+	for _, wr := range ps.writers {
+		if names[wr.name] {
+			removed = append(removed, wr)
+		} else {
+			writers = append(writers, wr)
+		}
+	}
+
+	ps.writers = writers
+
+	if !ps.started {
+		ps.mu.Unlock()
+
+		for _, wr := range removed {
+			wr.closeSinks()
+		}
+
+		return nil
+	}
+
+	// worker flushes its candles and closes outputs when input is closed.
+	close(w.in)
+	ps.mu.Unlock()
+
+	for _, wr := range removed {
+		<-wr.done
+	}
+
+	ps.l.Infof("Pipeline %s removed", name)
+
+	return nil
+}
+
+// Outputs returns names of outputs of added pipelines.
+func (ps *Pipelines) Outputs() []string {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	var names []string
+
+	for _, w := range ps.workers {
+		for _, o := range w.outputs() {
+			names = append(names, o.name)
+		}
+	}
+
+	return names
+}
+
+// workerIndex returns index of worker with provided name or -1 if there is no such worker.
+// Has to be called under lock.
+func (ps *Pipelines) workerIndex(name string) int {
+	for i := range ps.workers {
+		if ps.workers[i].name == name {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// startPipeline starts worker and its writers added after Init.
+// Has to be called under lock.
+func (ps *Pipelines) startPipeline(w *Worker, writers []*Writer) {
+	for _, wr := range writers {
+		ps.wg.Add(1)
+
+		go wr.startWriting(&ps.wg)
+	}
+
+	go w.start()
+}
+
+// restoreWorker restores worker state if pipelines are resumed.
+func (ps *Pipelines) restoreWorker(w *Worker) error {
+	if ps.resume == nil {
+		return nil
+	}
+
+	st, ok := ps.resume.Workers[w.name]
+	if !ok {
+		return errNotInCheckpoint
+	}
+
+	return w.restore(st)
+}
+
+// newWriter creates writer of worker output to the main sink and all extra sinks.
+func (ps *Pipelines) newWriter(w *Worker, o output) (*Writer, error) {
+	s, err := ps.newSink(w, o.name)
+	if err == nil {
+		err = ps.checkSync(s)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	wr := &Writer{
+		name: o.name,
+		data: o.data,
+		ctl:  make(chan func()),
+		done: make(chan struct{}),
+		l:    ps.l,
+	}
+
+	if err = wr.addSink("", s, ps.sinkOpts); err != nil {
+		s.Close()
+		return nil, err
+	}
+
+	for _, es := range ps.sinks {
+		s, err := ps.newExtraSink(es, o.name)
+		if err == nil {
+			err = ps.checkSync(s)
+		}
+
+		if err == nil {
+			if err = wr.addSink(es.name, s, es.opts); err != nil {
+				s.Close()
+			}
+		}
+
+		if err != nil {
+			wr.closeSinks()
+			return nil, err
+		}
+	}
+
+	return wr, nil
+}
+
+// checkSync closes sink and returns error if checkpoints are enabled
+// and the sink doesn't support them.
+func (ps *Pipelines) checkSync(s Sink) error {
+	if ps.cpPath == "" || canSync(s) {
+		return nil
+	}
+
+	s.Close()
+
+	return errCheckpointUnsupported
+}
+
+// newSink creates output of worker with provided name,
+// restoring output state if pipelines are resumed.
+func (ps *Pipelines) newSink(w *Worker, name string) (Sink, error) {
+	if ps.resume == nil && ps.append {
+		return ps.appendSink(w)
+	}
+
+	if ps.resume == nil {
+		return ps.wb.New(name)
+	}
+
+	size, ok := ps.resume.Outputs[name]
+	if !ok {
+		return nil, errNotInCheckpoint
+	}
+
+	return ps.wb.(resumableBuilder).Open(name, size)
+}
+
+// SetParsers sets count of goroutines parsing trades concurrently.
+// Must be called before Init.
+func (ps *Pipelines) SetParsers(n int) {
+	if n < 1 {
+		n = 1
+	}
+
+	ps.parsers = n
+}
+
+// SetBuffers sets count of trades buffered for each pipeline
+// and count of batches buffered between pipeline and its writer.
+// Must be called before Add.
+func (ps *Pipelines) SetBuffers(in, out int) {
+	if in < 0 {
+		in = 0
+	}
+
+	if out < 0 {
+		out = 0
+	}
+
+	ps.inBuffer, ps.outBuffer = in, out
+}
+
 // Init inits pipeline and waits signal for start reading from fileReader.
 func (ps *Pipelines) Init() {
+	ps.mu.Lock()
+	ps.started = true
+	// pipelines added after Init are not restored from checkpoint.
+	ps.resume = nil
+
 	// pipeline stage 3
-	go ps.startFileWriters()
+	ps.startFileWriters()
 
 	// pipeline stage 2
 	for _, w := range ps.workers {
 		go w.start()
 	}
 
+	ps.mu.Unlock()
+
 	go ps.startDataProcess()
 
 	// pipeline stage 1
@@ -115,48 +423,206 @@ func (ps *Pipelines) Init() {
 // startDataProcess represents start of stage two of pipeline:
 // parse trade and sent to workers.
 func (ps *Pipelines) startDataProcess() {
-	for s := range ps.r.C() {
-		tr, err := candles.TradeFromString(s)
-		if err != nil {
-			ps.l.Errorf("error parsing trade: %s, %v", s, err)
-			continue
+	lastCheckpoint := time.Now()
+
+	for b := range ps.parseBatches() {
+		if ps.validator != nil {
+			b = ps.validateBatch(b)
 		}
 
-		if !inWorkingRange(tr.Timestamp) {
-			ps.l.Debug("trade is not inside working hours range, skipping", tr)
-			continue
+		if ps.quarantine != nil && len(b.rejects) > 0 {
+			if err := ps.quarantine.write(b.rejects); err != nil {
+				ps.l.Errorf("can't write rejected lines to quarantine file: %v", err)
+			}
 		}
 
-		for i := range ps.workers {
-			ps.workers[i].in <- tr
+		// pipelines are not added or removed while batch is dispatched.
+		ps.mu.RLock()
+
+		for _, tr := range b.trades {
+			for i := range ps.workers {
+				ps.workers[i].in <- tr
+			}
+		}
+
+		if ps.cpPath != "" && time.Since(lastCheckpoint) >= ps.cpEvery {
+			if err := ps.checkpoint(b.offset); err != nil {
+				ps.l.Errorf("can't save checkpoint: %v", err)
+			}
+
+			lastCheckpoint = time.Now()
+		}
+
+		ps.mu.RUnlock()
+	}
+
+	if ps.quarantine != nil {
+		if err := ps.quarantine.close(); err != nil {
+			ps.l.Errorf("can't close quarantine file: %v", err)
 		}
 	}
 
+	ps.mu.Lock()
+	ps.stopped = true
+
 	for i := range ps.workers {
 		close(ps.workers[i].in)
 	}
 
+	ps.mu.Unlock()
+	ps.wg.Done()
+
 	ps.FileDone <- struct{}{}
 	close(ps.FileDone)
 }
 
+// parsedBatch contains trades parsed from files.Batch.
+type parsedBatch struct {
+	trades []candles.Trade
+	// raw contains input lines of trades if trades are validated,
+	// lines contains their numbers if they are known.
+	raw    [][]byte
+	lines  []int64
+	source string
+	// rejects contains lines which can't be parsed or are rejected by validation.
+	rejects []rejectedLine
+	offset  int64
+}
+
+// parseBatches parses batches from fileReader concurrently.
+// Trades are sent to returned chan in the order batches were read.
+func (ps *Pipelines) parseBatches() <-chan parsedBatch {
+	type job struct {
+		b   files.Batch
+		res chan parsedBatch
+	}
+
+	jobs := make(chan job)
+	queue := make(chan chan parsedBatch, ps.parsers)
+	out := make(chan parsedBatch)
+
+	for i := 0; i < ps.parsers; i++ {
+		go func() {
+			p := candles.NewParser()
+			for j := range jobs {
+				j.res <- ps.parseBatch(p, j.b)
+			}
+		}()
+	}
+
+	go func() {
+		for b := range ps.r.C() {
+			res := make(chan parsedBatch, 1)
+			queue <- res
+			jobs <- job{b: b, res: res}
+		}
+
+		close(jobs)
+		close(queue)
+	}()
+
+	go func() {
+		for res := range queue {
+			out <- <-res
+		}
+
+		close(out)
+	}()
+
+	return out
+}
+
+// parseBatch parses trades of a single batch,
+// skipping invalid ones and ones outside of working hours.
+// Invalid lines are returned as rejected.
+func (ps *Pipelines) parseBatch(p *candles.Parser, b files.Batch) parsedBatch {
+	var (
+		trades  = make([]candles.Trade, 0, len(b.Lines))
+		raw     [][]byte
+		lines   []int64
+		rejects []rejectedLine
+	)
+
+	// input lines are kept to quarantine trades rejected by validation.
+	if ps.validator != nil {
+		raw = make([][]byte, 0, len(b.Lines))
+
+		if b.Line > 0 {
+			lines = make([]int64, 0, len(b.Lines))
+		}
+	}
+
+	for i, line := range b.Lines {
+		tr, err := p.Parse(line)
+		if err != nil {
+			r := rejectedLine{Source: b.Source, Reason: parseReason(err), Error: err.Error(), Data: string(line)}
+			if b.Line > 0 {
+				r.Line = b.Line + int64(i)
+			}
+
+			ps.l.Errorf("error parsing trade at %s: %s, %v", r.position(), line, err)
+
+			rejects = append(rejects, r)
+
+			continue
+		}
+
+		if !ps.session.contains(tr.Timestamp) {
+			ps.l.Debug("trade is not inside working hours range, skipping", tr)
+			continue
+		}
+
+		trades = append(trades, tr)
+
+		if raw != nil {
+			raw = append(raw, line)
+		}
+
+		if lines != nil {
+			lines = append(lines, b.Line+int64(i))
+		}
+	}
+
+	return parsedBatch{trades: trades, raw: raw, lines: lines, source: b.Source, rejects: rejects, offset: b.Offset}
+}
+
 // startFileWriters represents start of stage three of pipeline:
 // write data to corresponding files.
+// Stage is done when all trades are dispatched and all writers are done.
+// Has to be called under lock.
 func (ps *Pipelines) startFileWriters() {
-	wg := &sync.WaitGroup{}
+	// released by stage two, so writers of pipelines added later are waited too.
+	ps.wg.Add(1)
 
 	for _, w := range ps.writers {
-		wg.Add(1)
+		ps.wg.Add(1)
 
-		go w.startWriting(wg)
+		go w.startWriting(&ps.wg)
 	}
 
-	wg.Wait()
+	go func() {
+		ps.wg.Wait()
+
+		if ps.append {
+			ps.saveAppendStates()
+		}
 
-	ps.Done <- struct{}{}
-	close(ps.Done)
+		ps.Done <- struct{}{}
+		close(ps.Done)
+	}()
 }
 
-func inWorkingRange(t time.Time) bool {
-	return !(t.Hour() >= endHours && t.Hour() < startHours)
+// SetWorkingHours sets hours of day working range starts and ends at,
+// range passes midnight if start is after end.
+// Trades outside of working range are skipped and intervals start at its start.
+// Must be called before Add.
+func (ps *Pipelines) SetWorkingHours(start, end int) error {
+	s, err := newSession(start, end)
+	if err != nil {
+		return err
+	}
+
+	ps.session = s
+
+	return nil
 }
@@ -0,0 +1,152 @@
+package pipelines
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/candles/files"
+	"github.com/candles/pipelines/candles"
+)
+
+// readerMock sends predefined batches.
+type readerMock struct {
+	c chan files.Batch
+}
+
+func (r readerMock) C() chan files.Batch {
+	return r.c
+}
+
+func (r readerMock) StartChan() chan struct{} {
+	return nil
+}
+
+func (r readerMock) Init() {}
+
+func TestPipelines_Internal_parseBatches(t *testing.T) {
+	const wantFmt = "TICKER,2019-01-30T11:00:00Z,%[1]d.000000,%[1]d.000000,%[1]d.000000,%[1]d.000000"
+
+	type args struct {
+		parsers int
+		batches int
+	}
+
+	tests := []struct {
+		name string
+		args args
+	}{
+		{
+			name: "single parser",
+			args: args{parsers: 1, batches: 10},
+		},
+		{
+			name: "multiple parsers keep order",
+			args: args{parsers: 8, batches: 100},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := readerMock{c: make(chan files.Batch)}
+			ps := New(r, WriterBuilder{}, logrus.New())
+			ps.SetParsers(test.args.parsers)
+
+			want := make([]string, 0, test.args.batches)
+			for i := 0; i < test.args.batches; i++ {
+				want = append(want, fmt.Sprintf(wantFmt, i))
+			}
+
+			go func() {
+				for i := 0; i < test.args.batches; i++ {
+					r.c <- files.Batch{Lines: [][]byte{
+						[]byte(fmt.Sprintf("TICKER,%d,10,2019-01-30 11:00:00", i)),
+						[]byte("invalid line"),
+						[]byte(fmt.Sprintf("TICKER,%d,10,2019-01-30 04:00:00", i)),
+					}}
+				}
+				close(r.c)
+			}()
+
+			got := make([]string, 0, test.args.batches)
+			for b := range ps.parseBatches() {
+				for _, tr := range b.trades {
+					got = append(got, candles.New(tr, tr.Timestamp).String())
+				}
+			}
+
+			assert.Equal(t, want, got)
+		})
+	}
+}
+
+func TestPipelines_Internal_AddRemove(t *testing.T) {
+	r := readerMock{c: make(chan files.Batch)}
+	b := sinkBuilderMock{sinks: make(map[string]*sinkMock)}
+
+	ps := New(r, b, logrus.New())
+	assert.NoError(t, ps.Add(5))
+
+	go ps.Init()
+
+	r.c <- files.Batch{Lines: [][]byte{[]byte("TICKER,100,10,2019-01-30 11:01:00")}}
+
+	// pipeline added at runtime skips trades until the next interval.
+	assert.NoError(t, ps.Add(10, WithHeikinAshi()))
+	assert.Equal(t, errIntervalAlreadyExists, ps.Add(10))
+	assert.Equal(t, []string{"candle_5min", "candle_10min", "ha_candle_10min"}, ps.Outputs())
+
+	r.c <- files.Batch{Lines: [][]byte{
+		[]byte("TICKER,200,10,2019-01-30 11:02:00"),
+		[]byte("TICKER,300,10,2019-01-30 11:11:00"),
+		[]byte("TICKER,400,10,2019-01-30 11:21:00"),
+	}}
+
+	assert.Eventually(t, func() bool {
+		c := ps.Snapshot()["candle_10min"]
+		return len(c) == 1 && c[0].StartTime().Equal(mustParseTime("2019-01-30 11:20:00.000000"))
+	}, time.Second, time.Millisecond)
+
+	// removed pipeline flushes not closed candle and closes its sink.
+	assert.NoError(t, ps.Remove("candle_5min"))
+	assert.Equal(t, errPipelineNotFound, ps.Remove("candle_5min"))
+	assert.Equal(t, []string{"candle_10min", "ha_candle_10min"}, ps.Outputs())
+	assert.Equal(t, 3, b.sinks["candle_5min"].written())
+	assert.True(t, b.sinks["candle_5min"].closed)
+
+	close(r.c)
+
+	<-ps.FileDone
+	<-ps.Done
+
+	assert.Equal(t, errPipelinesStopped, ps.Add(30))
+	assert.Equal(t, errPipelinesStopped, ps.Remove("candle_10min"))
+
+	got := b.sinks["candle_10min"]
+	assert.Equal(t, 2, got.written())
+	assert.Equal(t, "TICKER,2019-01-30T11:10:00Z,300.000000,300.000000,300.000000,300.000000", got.batches[0].String())
+	assert.True(t, got.closed)
+}
+
+func TestPipelines_SetWorkingHours(t *testing.T) {
+	ps := New(readerMock{}, sinkBuilderMock{sinks: make(map[string]*sinkMock)}, logrus.New())
+
+	assert.Equal(t, errInvalidHours, ps.SetWorkingHours(24, 3))
+	assert.Equal(t, errInvalidHours, ps.SetWorkingHours(10, -1))
+
+	assert.NoError(t, ps.SetWorkingHours(8, 20))
+	assert.True(t, ps.session.contains(mustParseTime("2019-01-30 08:00:00.000000")))
+	assert.True(t, ps.session.contains(mustParseTime("2019-01-30 19:59:00.000000")))
+	assert.False(t, ps.session.contains(mustParseTime("2019-01-30 20:00:00.000000")))
+	assert.False(t, ps.session.contains(mustParseTime("2019-01-30 07:59:00.000000")))
+
+	assert.NoError(t, ps.Add(5))
+	assert.Equal(t, ps.session, ps.workers[0].session)
+
+	assert.NoError(t, ps.SetWorkingHours(10, 3))
+	assert.True(t, ps.session.contains(mustParseTime("2019-01-30 02:59:00.000000")))
+	assert.False(t, ps.session.contains(mustParseTime("2019-01-30 03:00:00.000000")))
+}
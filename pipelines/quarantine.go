@@ -0,0 +1,103 @@
+package pipelines
+
+import (
+	"encoding/json"
+	"os"
+	"strconv"
+
+	"github.com/candles/pipelines/candles"
+)
+
+// parseReasons are stable names of parse errors, which rejected lines can be grouped by.
+var parseReasons = map[error]string{
+	candles.ErrInvalidTicker: "invalid_ticker",
+	candles.ErrInvalidValue:  "invalid_value",
+	candles.ErrInvalidPrice:  "invalid_price",
+	candles.ErrInvalidCount:  "invalid_count",
+	candles.ErrInvalidTime:   "invalid_time",
+}
+
+// parseReason returns stable name of parse error.
+func parseReason(err error) string {
+	if reason, ok := parseReasons[err]; ok {
+		return reason
+	}
+
+	return "parse_error"
+}
+
+// rejectedLine describes input line which can't be parsed into trade or is rejected by validation.
+type rejectedLine struct {
+	// Source is the name of input the line is read from.
+	Source string `json:"source,omitempty"`
+	// Line is the line number in input, zero if it is unknown.
+	Line int64 `json:"line,omitempty"`
+	// Reason is the stable name of parse error or of validation rule line is rejected by,
+	// e.g. "invalid_price" or "positive_count".
+	Reason string `json:"reason"`
+	// Error is the error message, e.g. "invalid price".
+	Error string `json:"error"`
+	Data  string `json:"data"`
+}
+
+// position returns source and line number of rejected line for logs.
+func (r rejectedLine) position() string {
+	if r.Line == 0 {
+		return r.Source
+	}
+
+	return r.Source + ":" + strconv.FormatInt(r.Line, 10)
+}
+
+// quarantine writes rejected lines to file as JSON lines,
+// so they can be fixed and replayed.
+type quarantine struct {
+	f   *os.File
+	enc *json.Encoder
+}
+
+// openQuarantine opens quarantine file in append mode, creating it if it doesn't exist.
+func openQuarantine(path string) (*quarantine, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	return &quarantine{f: f, enc: json.NewEncoder(f)}, nil
+}
+
+// write writes rejected lines to file.
+func (q *quarantine) write(rejects []rejectedLine) error {
+	for _, r := range rejects {
+		if err := q.enc.Encode(r); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// sync commits written lines to storage.
+func (q *quarantine) sync() error {
+	return q.f.Sync()
+}
+
+// close closes file.
+func (q *quarantine) close() error {
+	return q.f.Close()
+}
+
+// EnableQuarantine makes pipelines write input lines, which can't be parsed into trades,
+// to file by path along with their source, line number and parse error.
+// File is appended to, so lines rejected after the last checkpoint are written again on resume.
+// Must be called before Init.
+func (ps *Pipelines) EnableQuarantine(path string) error {
+	q, err := openQuarantine(path)
+	if err != nil {
+		return err
+	}
+
+	ps.quarantine = q
+
+	return nil
+}
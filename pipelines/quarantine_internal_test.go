@@ -0,0 +1,98 @@
+package pipelines
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/candles/files"
+	"github.com/candles/pipelines/candles"
+)
+
+func TestPipelines_Internal_quarantine(t *testing.T) {
+	dir, err := ioutil.TempDir("", "quarantine")
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		name  string
+		batch files.Batch
+		want  []rejectedLine
+	}{
+		{
+			name: "known line numbers",
+			batch: files.Batch{
+				Source: "trades.csv",
+				Line:   10,
+				Lines: [][]byte{
+					[]byte("TICKER,10,10,2019-01-30 11:00:00"),
+					[]byte(",10,10,2019-01-30 11:00:00"),
+					[]byte("TICKER,10,10,2019-01-30 11:00:00"),
+					[]byte("TICKER,10,x,2019-01-30 11:00:00"),
+				},
+			},
+			want: []rejectedLine{
+				{Source: "trades.csv", Line: 11, Reason: "invalid_ticker", Error: candles.ErrInvalidTicker.Error(), Data: ",10,10,2019-01-30 11:00:00"},
+				{Source: "trades.csv", Line: 13, Reason: "invalid_count", Error: candles.ErrInvalidCount.Error(), Data: "TICKER,10,x,2019-01-30 11:00:00"},
+			},
+		},
+		{
+			name: "unknown line numbers",
+			batch: files.Batch{
+				Source: "trades",
+				Lines:  [][]byte{[]byte("TICKER,10,10,invalid")},
+			},
+			want: []rejectedLine{
+				{Source: "trades", Reason: "invalid_time", Error: candles.ErrInvalidTime.Error(), Data: "TICKER,10,10,invalid"},
+			},
+		},
+		{
+			name: "no rejected lines",
+			batch: files.Batch{
+				Source: "trades.csv",
+				Line:   1,
+				Lines:  [][]byte{[]byte("TICKER,10,10,2019-01-30 11:00:00")},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(dir, test.name+".jsonl")
+
+			ps := New(readerMock{}, WriterBuilder{}, logrus.New())
+			assert.NoError(t, ps.EnableQuarantine(path))
+
+			b := ps.parseBatch(candles.NewParser(), test.batch)
+			assert.Equal(t, test.want, b.rejects)
+
+			assert.NoError(t, ps.quarantine.write(b.rejects))
+			assert.NoError(t, ps.quarantine.close())
+
+			f, err := os.Open(path)
+			assert.NoError(t, err)
+
+			defer f.Close()
+
+			var got []rejectedLine
+
+			s := bufio.NewScanner(f)
+			for s.Scan() {
+				var r rejectedLine
+				assert.NoError(t, json.Unmarshal(s.Bytes(), &r))
+
+				got = append(got, r)
+			}
+
+			assert.NoError(t, s.Err())
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
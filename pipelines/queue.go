@@ -0,0 +1,319 @@
+package pipelines
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+)
+
+var errQueueClosed = errors.New("queue is closed")
+
+// OverflowPolicy defines what happens to batches sent to a full sink queue.
+type OverflowPolicy int
+
+// OverflowPolicy values.
+const (
+	// BlockOnFull blocks sending until the sink writes a queued batch.
+	BlockOnFull OverflowPolicy = iota
+	// DropOldest drops the oldest queued batch to make room for the new one.
+	DropOldest
+	// SpillToDisk writes batches to a temporary file until the sink catches up.
+	SpillToDisk
+)
+
+// batchQueue is a bounded FIFO queue of batches with overflow policy.
+// Queue is written by a single producer and read by a single consumer.
+// Spill file is written and read without holding queue lock.
+type batchQueue struct {
+	mu      sync.Mutex
+	notFull *sync.Cond
+	items   []Batch
+	size    int
+	policy  OverflowPolicy
+	spill   *spillFile
+	// unread is the count of spilled batches not popped yet,
+	// spilling reports whether producer is writing batch to spill file.
+	unread   int
+	spilling bool
+	closed   bool
+	dropped  int64
+	spilled  int64
+	// ready is signaled when batches are pushed or queue is closed.
+	ready chan struct{}
+}
+
+// newBatchQueue creates new batchQueue of provided size.
+// Spill file is created in spillDir if policy is SpillToDisk.
+func newBatchQueue(size int, policy OverflowPolicy, spillDir string) (*batchQueue, error) {
+	if size < 1 {
+		size = 1
+	}
+
+	q := &batchQueue{
+		items:  make([]Batch, 0, size),
+		size:   size,
+		policy: policy,
+		ready:  make(chan struct{}, 1),
+	}
+	q.notFull = sync.NewCond(&q.mu)
+
+	if policy == SpillToDisk {
+		s, err := newSpillFile(spillDir)
+		if err != nil {
+			return nil, err
+		}
+
+		q.spill = s
+	}
+
+	return q, nil
+}
+
+// push adds batch to queue, applying overflow policy if queue is full.
+func (q *batchQueue) push(b Batch) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return errQueueClosed
+	}
+
+	switch {
+	case q.spill != nil && (q.unread > 0 || q.spilling || len(q.items) >= q.size):
+		// spilled batches are newer than queued ones, so new batches follow them.
+		q.spilling = true
+		q.mu.Unlock()
+
+		err := q.spill.write(b)
+
+		q.mu.Lock()
+		q.spilling = false
+
+		if err != nil {
+			return err
+		}
+
+		q.unread++
+		q.spilled++
+	case q.policy == DropOldest && len(q.items) >= q.size:
+		q.items = append(q.items[1:], b)
+		q.dropped++
+	default:
+		for len(q.items) >= q.size && !q.closed {
+			q.notFull.Wait()
+		}
+
+		q.items = append(q.items, b)
+	}
+
+	q.signal()
+
+	return nil
+}
+
+// pop removes the oldest batch from queue without blocking.
+// Reports false if queue is empty or spilled batch can't be read.
+// Batch is returned along with error if spill file can't be reused after it.
+func (q *batchQueue) pop() (Batch, bool, error) {
+	q.mu.Lock()
+
+	if len(q.items) > 0 {
+		b := q.items[0]
+		q.items[0] = Batch{}
+		q.items = q.items[1:]
+		q.notFull.Signal()
+		q.mu.Unlock()
+
+		return b, true, nil
+	}
+
+	if q.spill == nil || q.unread == 0 {
+		q.mu.Unlock()
+		return Batch{}, false, nil
+	}
+
+	q.mu.Unlock()
+
+	b, err := q.spill.read()
+	if err != nil {
+		q.mu.Lock()
+		defer q.mu.Unlock()
+
+		// spilled batches can't be read anymore.
+		q.dropped += int64(q.unread)
+		q.unread = 0
+		_ = q.spill.discard()
+
+		return Batch{}, false, err
+	}
+
+	q.mu.Lock()
+	q.unread--
+	q.mu.Unlock()
+
+	return b, true, q.spill.release()
+}
+
+// close closes queue, batches left in queue can still be popped.
+func (q *batchQueue) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.closed = true
+	q.notFull.Broadcast()
+	q.signal()
+}
+
+// done reports whether queue is closed and empty.
+func (q *batchQueue) done() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.closed && len(q.items) == 0 && q.unread == 0 && !q.spilling
+}
+
+// removeSpill removes spill file of queue.
+func (q *batchQueue) removeSpill() {
+	if q.spill != nil {
+		q.spill.remove()
+	}
+}
+
+// stats returns count of batches in queue, count of dropped and spilled batches.
+func (q *batchQueue) stats() (depth int, dropped, spilled int64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	depth = len(q.items) + q.unread
+
+	return depth, q.dropped, q.spilled
+}
+
+// signal wakes up the consumer. Has to be called under lock.
+func (q *batchQueue) signal() {
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+// spillFile keeps batches in temporary file as JSON lines.
+// It is safe to write and read file concurrently.
+type spillFile struct {
+	mu sync.Mutex
+	w  *os.File
+	r  *os.File
+	b  *bufio.Reader
+	// n is the count of written and not released batches.
+	n int
+}
+
+// newSpillFile creates new spillFile in dir.
+func newSpillFile(dir string) (*spillFile, error) {
+	w, err := ioutil.TempFile(dir, "candles-spill-*.jsonl")
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := os.Open(w.Name())
+	if err != nil {
+		_ = w.Close()
+		_ = os.Remove(w.Name())
+
+		return nil, err
+	}
+
+	return &spillFile{w: w, r: r, b: bufio.NewReader(r)}, nil
+}
+
+// write appends batch to file.
+func (s *spillFile) write(b Batch) error {
+	data, err := json.Marshal(b)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err = s.w.Write(append(data, '\n')); err != nil {
+		return err
+	}
+
+	s.n++
+
+	return nil
+}
+
+// read reads the oldest written batch, which has to be released after it is handled.
+func (s *spillFile) read() (Batch, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	line, err := s.b.ReadBytes('\n')
+	if err != nil {
+		return Batch{}, err
+	}
+
+	var b Batch
+	if err = json.Unmarshal(line, &b); err != nil {
+		return Batch{}, err
+	}
+
+	return b, nil
+}
+
+// release marks the oldest batch as read.
+// File is truncated when all written batches are released.
+func (s *spillFile) release() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.n--
+
+	if s.n > 0 {
+		return nil
+	}
+
+	return s.reset()
+}
+
+// discard drops all written batches.
+func (s *spillFile) discard() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.n = 0
+
+	return s.reset()
+}
+
+// reset truncates file and rewinds reader. Has to be called under lock.
+func (s *spillFile) reset() error {
+	if err := s.w.Truncate(0); err != nil {
+		return err
+	}
+
+	if _, err := s.w.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	if _, err := s.r.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	s.b.Reset(s.r)
+
+	return nil
+}
+
+// remove closes and removes file.
+func (s *spillFile) remove() {
+	_ = s.r.Close()
+	_ = s.w.Close()
+	_ = os.Remove(s.w.Name())
+}
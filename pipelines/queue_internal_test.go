@@ -0,0 +1,201 @@
+package pipelines
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/candles/pipelines/candles"
+)
+
+// popAll pops all batches from queue and returns their outputs.
+func popAll(t *testing.T, q *batchQueue) []string {
+	var got []string
+
+	for {
+		b, ok, err := q.pop()
+		assert.NoError(t, err)
+
+		if !ok {
+			return got
+		}
+
+		got = append(got, b.Output)
+	}
+}
+
+func TestBatchQueue_Internal_dropOldest(t *testing.T) {
+	q, err := newBatchQueue(2, DropOldest, "")
+	assert.NoError(t, err)
+
+	for _, name := range []string{"a", "b", "c", "d"} {
+		assert.NoError(t, q.push(Batch{Output: name}))
+	}
+
+	depth, dropped, spilled := q.stats()
+	assert.Equal(t, 2, depth)
+	assert.Equal(t, int64(2), dropped)
+	assert.Equal(t, int64(0), spilled)
+
+	assert.Equal(t, []string{"c", "d"}, popAll(t, q))
+}
+
+func TestBatchQueue_Internal_spillToDisk(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spill")
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	q, err := newBatchQueue(2, SpillToDisk, dir)
+	assert.NoError(t, err)
+
+	iStart := mustParseTime("2019-01-30 11:00:00.000000")
+	c := candles.New(candles.MustTradeFromString("TICKER,100,10,2019-01-30 11:00:01"), iStart)
+
+	assert.NoError(t, q.push(Batch{Output: "a"}))
+	assert.NoError(t, q.push(Batch{Output: "b"}))
+	assert.NoError(t, q.push(Batch{Output: "c", Candles: []candles.Candle{*c}, Columns: [][]string{{"final"}}}))
+
+	b, ok, err := q.pop()
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "a", b.Output)
+
+	// queue has room, but new batches follow spilled ones.
+	assert.NoError(t, q.push(Batch{Output: "d"}))
+
+	depth, _, spilled := q.stats()
+	assert.Equal(t, 3, depth)
+	assert.Equal(t, int64(2), spilled)
+
+	assert.Equal(t, "b", popOutput(t, q))
+
+	b, ok, err = q.pop()
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "c", b.Output)
+	assert.Equal(t, c.String()+",final", b.String())
+
+	assert.Equal(t, []string{"d"}, popAll(t, q))
+
+	// spill file is reused after it is read.
+	assert.NoError(t, q.push(Batch{Output: "e"}))
+	assert.NoError(t, q.push(Batch{Output: "f"}))
+	assert.NoError(t, q.push(Batch{Output: "g"}))
+	assert.Equal(t, []string{"e", "f", "g"}, popAll(t, q))
+
+	q.close()
+	assert.True(t, q.done())
+	q.removeSpill()
+
+	files, err := ioutil.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Empty(t, files)
+}
+
+func TestBatchQueue_Internal_spillIO(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spill")
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	q, err := newBatchQueue(1, SpillToDisk, dir)
+	assert.NoError(t, err)
+
+	defer q.removeSpill()
+
+	for _, name := range []string{"a", "b", "c"} {
+		assert.NoError(t, q.push(Batch{Output: name}))
+	}
+
+	assert.Equal(t, "a", popOutput(t, q))
+
+	// queue lock isn't held while spill file is read.
+	q.spill.mu.Lock()
+
+	popped := make(chan string)
+	go func() {
+		popped <- popOutput(t, q)
+	}()
+
+	statsDone := make(chan struct{})
+	go func() {
+		q.stats()
+		close(statsDone)
+	}()
+
+	select {
+	case <-statsDone:
+	case <-time.After(time.Second):
+		t.Fatal("queue is locked during spill file IO")
+	}
+
+	q.spill.mu.Unlock()
+	assert.Equal(t, "b", <-popped)
+
+	// batch is returned even if spill file can't be truncated after it.
+	assert.NoError(t, q.spill.w.Close())
+
+	b, ok, err := q.pop()
+	assert.Error(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "c", b.Output)
+
+	q.close()
+	assert.True(t, q.done())
+}
+
+// popOutput pops a single batch from queue and returns its output.
+func popOutput(t *testing.T, q *batchQueue) string {
+	b, ok, err := q.pop()
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	return b.Output
+}
+
+func TestBatchQueue_Internal_block(t *testing.T) {
+	q, err := newBatchQueue(1, BlockOnFull, "")
+	assert.NoError(t, err)
+
+	assert.NoError(t, q.push(Batch{Output: "a"}))
+
+	pushed := make(chan struct{})
+
+	go func() {
+		assert.NoError(t, q.push(Batch{Output: "b"}))
+		close(pushed)
+	}()
+
+	select {
+	case <-pushed:
+		t.Fatal("push to full queue is not blocked")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	assert.Equal(t, "a", popOutput(t, q))
+	<-pushed
+	assert.Equal(t, []string{"b"}, popAll(t, q))
+
+	q.close()
+	assert.Equal(t, errQueueClosed, q.push(Batch{}))
+}
+
+func TestPipelines_Internal_QueueStats(t *testing.T) {
+	ps := New(readerMock{}, sinkBuilderMock{sinks: make(map[string]*sinkMock)}, logrus.New())
+	ps.SetBuffers(8, 2)
+	ps.SetSinkOptions(WithBuffer(4))
+	assert.NoError(t, ps.AddSink("copy", sinkBuilderMock{sinks: make(map[string]*sinkMock)}, WithBuffer(1)))
+	assert.NoError(t, ps.Add(5))
+
+	assert.Equal(t, []QueueStats{
+		{Name: "in/candle_5min", Capacity: 8},
+		{Name: "out/candle_5min", Capacity: 2},
+		{Name: "sink/candle_5min", Capacity: 4},
+		{Name: "sink/copy/candle_5min", Capacity: 1},
+	}, ps.QueueStats())
+}
@@ -0,0 +1,40 @@
+package pipelines
+
+import (
+	"errors"
+	"time"
+)
+
+var errInvalidHours = errors.New("invalid working hours")
+
+// defaultSession is the working range from 10:00 to 03:00 of the next day.
+var defaultSession = session{start: 10, end: 3}
+
+// session describes hours of day trades are aggregated within.
+type session struct {
+	start int
+	end   int
+}
+
+// newSession creates session starting and ending at provided hours of day.
+func newSession(start, end int) (session, error) {
+	const hoursInDay = 24
+
+	if start < 0 || start >= hoursInDay || end < 0 || end >= hoursInDay {
+		return session{}, errInvalidHours
+	}
+
+	return session{start: start, end: end}, nil
+}
+
+// contains reports whether t is inside working range.
+func (s session) contains(t time.Time) bool {
+	h := t.Hour()
+
+	if s.start <= s.end {
+		// working range is within a single day, or the whole day if start equals end.
+		return s.start == s.end || h >= s.start && h < s.end
+	}
+
+	return !(h >= s.end && h < s.start)
+}
@@ -0,0 +1,259 @@
+package pipelines
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	errInvalidSinkName   = errors.New("invalid sink name")
+	errSinkAlreadyExists = errors.New("sink already exists")
+)
+
+// defaultSinkBuffer is the default count of batches queued for a sink.
+const defaultSinkBuffer = 16
+
+// ErrorPolicy defines how sink write errors are handled after retries.
+type ErrorPolicy int
+
+// ErrorPolicy values.
+const (
+	// LogErrors logs write error and drops the batch.
+	LogErrors ErrorPolicy = iota
+	// DisableOnError logs write error and stops writing to the sink.
+	DisableOnError
+)
+
+// SinkOption configures sink of pipelines outputs.
+type SinkOption func(o *sinkOptions)
+
+// sinkOptions describes buffering and error handling of sink.
+type sinkOptions struct {
+	buffer     int
+	overflow   OverflowPolicy
+	spillDir   string
+	policy     ErrorPolicy
+	retries    int
+	retryDelay time.Duration
+}
+
+// WithBuffer sets count of batches queued for sink,
+// so a slow sink doesn't stall pipeline until its queue is full.
+func WithBuffer(n int) SinkOption {
+	return func(o *sinkOptions) {
+		o.buffer = n
+	}
+}
+
+// WithOverflow sets handling of batches sent to sink with full queue.
+func WithOverflow(p OverflowPolicy) SinkOption {
+	return func(o *sinkOptions) {
+		o.overflow = p
+	}
+}
+
+// WithSpillDir sets directory of files batches are spilled to.
+// Temporary directory is used by default.
+func WithSpillDir(dir string) SinkOption {
+	return func(o *sinkOptions) {
+		o.spillDir = dir
+	}
+}
+
+// WithErrorPolicy sets handling of sink write errors.
+func WithErrorPolicy(p ErrorPolicy) SinkOption {
+	return func(o *sinkOptions) {
+		o.policy = p
+	}
+}
+
+// WithRetries makes failed writes to sink retried n times with provided delay
+// before error policy is applied.
+func WithRetries(n int, delay time.Duration) SinkOption {
+	return func(o *sinkOptions) {
+		o.retries = n
+		o.retryDelay = delay
+	}
+}
+
+// newSinkOptions returns sink options with defaults overridden by opts.
+func newSinkOptions(opts ...SinkOption) sinkOptions {
+	o := sinkOptions{buffer: defaultSinkBuffer}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
+
+// SetSinkOptions configures the main sink of pipelines outputs.
+// Must be called before Add.
+func (ps *Pipelines) SetSinkOptions(opts ...SinkOption) {
+	ps.sinkOpts = newSinkOptions(opts...)
+}
+
+// extraSink describes sink added to all pipelines outputs along with the main one.
+type extraSink struct {
+	name string
+	wb   WritersBuilder
+	opts sinkOptions
+}
+
+// AddSink makes all pipelines outputs also be written to sinks created by wb.
+// In checkpoints outputs of the sink are named with the sink name prefix.
+// Outputs of the sink are recreated in append mode.
+// Must be called before Add.
+func (ps *Pipelines) AddSink(name string, wb WritersBuilder, opts ...SinkOption) error {
+	if name == "" {
+		return errInvalidSinkName
+	}
+
+	for _, es := range ps.sinks {
+		if es.name == name {
+			return errSinkAlreadyExists
+		}
+	}
+
+	ps.sinks = append(ps.sinks, extraSink{name: name, wb: wb, opts: newSinkOptions(opts...)})
+
+	return nil
+}
+
+// newExtraSink creates output of extra sink with provided name,
+// restoring output state if pipelines are resumed.
+func (ps *Pipelines) newExtraSink(es extraSink, name string) (Sink, error) {
+	if ps.resume == nil {
+		return es.wb.New(name)
+	}
+
+	rb, ok := es.wb.(resumableBuilder)
+	if !ok {
+		return nil, errResumeUnsupported
+	}
+
+	size, ok := ps.resume.Outputs[outputKey(es.name, name)]
+	if !ok {
+		return nil, errNotInCheckpoint
+	}
+
+	return rb.Open(name, size)
+}
+
+// outputKey returns name of sink output in checkpoint.
+// Outputs of the main sink are named by output names.
+func outputKey(sink, output string) string {
+	if sink == "" {
+		return output
+	}
+
+	return sink + "/" + output
+}
+
+// sinkWriter writes batches queued for a single sink.
+type sinkWriter struct {
+	name     string
+	s        Sink
+	opts     sinkOptions
+	q        *batchQueue
+	ctl      chan func()
+	disabled bool
+
+	l *logrus.Logger
+}
+
+// newSinkWriter creates new sinkWriter.
+func newSinkWriter(name string, s Sink, opts sinkOptions, l *logrus.Logger) (*sinkWriter, error) {
+	q, err := newBatchQueue(opts.buffer, opts.overflow, opts.spillDir)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sinkWriter{
+		name: name,
+		s:    s,
+		opts: opts,
+		q:    q,
+		ctl:  make(chan func()),
+		l:    l,
+	}, nil
+}
+
+// start writes queued batches until queue is closed and empty, then closes sink.
+// Functions received from control chan are executed after all batches queued before them are written.
+func (sw *sinkWriter) start(wg *sync.WaitGroup) {
+	defer wg.Done()
+	defer sw.q.removeSpill()
+	defer sw.s.Close()
+
+	for {
+		select {
+		case <-sw.q.ready:
+			sw.drain()
+
+			if sw.q.done() {
+				return
+			}
+		case f := <-sw.ctl:
+			sw.drain()
+			f()
+		}
+	}
+}
+
+// drain writes all queued batches.
+func (sw *sinkWriter) drain() {
+	for {
+		b, ok, err := sw.q.pop()
+		if err != nil {
+			sw.l.Errorf("spill file error of sink %q: %v", sw.name, err)
+		}
+
+		if !ok {
+			if err != nil {
+				continue
+			}
+
+			return
+		}
+
+		sw.write(b)
+	}
+}
+
+// write writes batch to sink, handling errors according to sink options.
+func (sw *sinkWriter) write(b Batch) {
+	if sw.disabled {
+		return
+	}
+
+	err := sw.s.Write(b)
+	for i := 0; err != nil && i < sw.opts.retries; i++ {
+		time.Sleep(sw.opts.retryDelay)
+		err = sw.s.Write(b)
+	}
+
+	if err == nil {
+		return
+	}
+
+	sw.l.Errorf("error writing %s to sink %q: %v", b.Output, sw.name, err)
+
+	if sw.opts.policy == DisableOnError {
+		sw.disabled = true
+		sw.l.Errorf("Sink %q of %s is disabled", sw.name, b.Output)
+	}
+}
+
+// do executes f inside sink writer goroutine and waits for it to complete.
+func (sw *sinkWriter) do(f func()) {
+	done := make(chan struct{})
+	sw.ctl <- func() {
+		f()
+		close(done)
+	}
+
+	<-done
+}
@@ -0,0 +1,223 @@
+package pipelines
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/candles/files"
+	"github.com/candles/pipelines/candles"
+)
+
+var errSinkMock = errors.New("sink failed")
+
+// sinkMock records written batches, failing first writes and delaying each write.
+type sinkMock struct {
+	mu      sync.Mutex
+	batches []Batch
+	fails   int
+	delay   time.Duration
+	closed  bool
+}
+
+func (s *sinkMock) Write(b Batch) error {
+	time.Sleep(s.delay)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.fails > 0 {
+		s.fails--
+		return errSinkMock
+	}
+
+	s.batches = append(s.batches, b)
+
+	return nil
+}
+
+func (s *sinkMock) Sync() (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return int64(len(s.batches)), nil
+}
+
+func (s *sinkMock) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+}
+
+func (s *sinkMock) written() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.batches)
+}
+
+// sinkBuilderMock creates sinkMock outputs.
+type sinkBuilderMock struct {
+	sinks map[string]*sinkMock
+	delay time.Duration
+}
+
+func (b sinkBuilderMock) New(name string) (Sink, error) {
+	s := &sinkMock{delay: b.delay}
+	b.sinks[name] = s
+
+	return s, nil
+}
+
+func TestWriter_Internal_fanOut(t *testing.T) {
+	l := logrus.New()
+	data := make(chan Batch)
+
+	fast := &sinkMock{}
+	slow := &sinkMock{delay: 50 * time.Millisecond}
+
+	w := NewWriter(fast, data, l)
+	assert.NoError(t, w.addSink("slow", slow, newSinkOptions(WithBuffer(3))))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+
+	go w.startWriting(wg)
+
+	// slow sink doesn't stall writer until its queue is full.
+	start := time.Now()
+
+	for i := 0; i < 3; i++ {
+		data <- Batch{Output: "candle_5min"}
+	}
+
+	assert.Less(t, int64(time.Since(start)), int64(50*time.Millisecond))
+
+	close(data)
+	wg.Wait()
+
+	assert.Equal(t, 3, fast.written())
+	assert.Equal(t, 3, slow.written())
+	assert.True(t, fast.closed)
+	assert.True(t, slow.closed)
+}
+
+func TestSinkWriter_Internal_write(t *testing.T) {
+	tests := []struct {
+		name  string
+		fails int
+		opts  []SinkOption
+		want  int
+	}{
+		{
+			name:  "log errors",
+			fails: 1,
+			want:  2,
+		},
+		{
+			name:  "retries",
+			fails: 2,
+			opts:  []SinkOption{WithRetries(2, time.Millisecond)},
+			want:  3,
+		},
+		{
+			name:  "disable on error",
+			fails: 1,
+			opts:  []SinkOption{WithErrorPolicy(DisableOnError)},
+			want:  0,
+		},
+		{
+			name:  "disable on error after retries",
+			fails: 1,
+			opts:  []SinkOption{WithErrorPolicy(DisableOnError), WithRetries(1, 0)},
+			want:  3,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := &sinkMock{fails: test.fails}
+			sw, err := newSinkWriter("test", s, newSinkOptions(test.opts...), logrus.New())
+			assert.NoError(t, err)
+
+			for i := 0; i < 3; i++ {
+				sw.write(Batch{Output: "candle_5min"})
+			}
+
+			assert.Equal(t, test.want, s.written())
+		})
+	}
+}
+
+func TestPipelines_Internal_AddSink(t *testing.T) {
+	ps := New(readerMock{}, WriterBuilder{}, logrus.New())
+
+	assert.Equal(t, errInvalidSinkName, ps.AddSink("", JSONWriterBuilder{}))
+	assert.NoError(t, ps.AddSink("json", JSONWriterBuilder{}))
+	assert.Equal(t, errSinkAlreadyExists, ps.AddSink("json", JSONWriterBuilder{}))
+}
+
+func TestPipelines_Internal_checkpointSinks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkpoint")
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "checkpoint.json")
+
+	r := readerMock{c: make(chan files.Batch)}
+	main := sinkBuilderMock{sinks: make(map[string]*sinkMock)}
+	slow := sinkBuilderMock{sinks: make(map[string]*sinkMock), delay: 20 * time.Millisecond}
+
+	ps := New(r, main, logrus.New())
+	ps.EnableCheckpoints(path, 0)
+	ps.SetBuffers(4, 4)
+	assert.NoError(t, ps.AddSink("slow", slow, WithBuffer(1), WithOverflow(SpillToDisk), WithSpillDir(dir)))
+	assert.NoError(t, ps.Add(5))
+
+	go ps.Init()
+
+	r.c <- files.Batch{Lines: [][]byte{[]byte("TICKER,100,10,2019-01-30 11:00:01")}, Offset: 1}
+	r.c <- files.Batch{Lines: [][]byte{
+		[]byte("TICKER,200,10,2019-01-30 11:06:00"),
+		[]byte("TICKER,300,10,2019-01-30 11:11:00"),
+	}, Offset: 2}
+	close(r.c)
+
+	<-ps.FileDone
+	<-ps.Done
+
+	cp, err := loadCheckpoint(path)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), cp.Offset)
+	assert.Equal(t, map[string]int64{"candle_5min": 2, "slow/candle_5min": 2}, cp.Outputs)
+
+	assert.Equal(t, 3, main.sinks["candle_5min"].written())
+	assert.Equal(t, 3, slow.sinks["candle_5min"].written())
+}
+
+func TestBatch_Internal_JSON(t *testing.T) {
+	iStart := mustParseTime("2019-01-30 11:00:00.000000")
+	c := candles.New(candles.MustTradeFromString("TICKER,100,10,2019-01-30 11:00:01"), iStart)
+
+	got, err := Batch{
+		Output:  "candle_5min",
+		Partial: true,
+		Candles: []candles.Candle{*c},
+		Columns: [][]string{{"partial"}},
+	}.JSON()
+	assert.NoError(t, err)
+	assert.Equal(t, `{"output":"candle_5min","partial":true,"candle":{"ticker":"TICKER",`+
+		`"start_time":"2019-01-30T11:00:00Z","open":100,"high":100,"low":100,"close":100,`+
+		`"trades":1,"volume":10,"turnover":1000,"open_time":"2019-01-30T11:00:01Z",`+
+		`"high_time":"2019-01-30T11:00:01Z","low_time":"2019-01-30T11:00:01Z",`+
+		`"close_time":"2019-01-30T11:00:01Z"},"columns":["partial"]}`, got)
+}
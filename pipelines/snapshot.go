@@ -0,0 +1,30 @@
+package pipelines
+
+import (
+	"github.com/candles/pipelines/candles"
+)
+
+// Snapshot returns candles of not closed intervals or bars of every pipeline
+// by pipeline name.
+// Stopped pipelines are omitted.
+// Must be called after Init.
+func (ps *Pipelines) Snapshot() map[string][]candles.Candle {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+
+	out := make(map[string][]candles.Candle, len(ps.workers))
+
+	for _, w := range ps.workers {
+		w := w
+
+		var c []candles.Candle
+
+		if w.do(func() {
+			c = w.openCandles()
+		}) {
+			out[w.name] = c
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,41 @@
+package pipelines
+
+import (
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/candles/pipelines/candles"
+)
+
+func TestPipelines_Internal_Snapshot(t *testing.T) {
+	ps := New(readerMock{}, WriterBuilder{}, logrus.New())
+
+	w := NewWorker(5)
+	w.name = "candle_5min"
+	ps.workers = append(ps.workers, w)
+
+	go w.start()
+
+	for _, s := range []string{
+		"TICKER,100,10,2019-01-30 11:00:01",
+		"TICKER,200,10,2019-01-30 11:01:00",
+	} {
+		w.in <- candles.MustTradeFromString(s)
+	}
+
+	got := ps.Snapshot()
+	assert.Len(t, got, 1)
+	assert.Len(t, got["candle_5min"], 1)
+	assert.Equal(t, "TICKER,2019-01-30T11:00:00Z,100.000000,200.000000,100.000000,200.000000",
+		got["candle_5min"][0].String())
+
+	close(w.in)
+
+	for range w.out {
+	}
+
+	<-w.done
+	assert.Empty(t, ps.Snapshot())
+}
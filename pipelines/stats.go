@@ -0,0 +1,46 @@
+package pipelines
+
+// QueueStats describes state of a queue between pipelines stages.
+type QueueStats struct {
+	// Name is the queue name: "in/" or "out/" followed by pipeline output name
+	// for pipeline input and output, "sink/" followed by checkpoint output name for sinks.
+	Name string `json:"name"`
+	// Depth is the count of queued items, including spilled ones.
+	Depth int `json:"depth"`
+	// Capacity is the count of items queue holds in memory.
+	Capacity int   `json:"capacity"`
+	Dropped  int64 `json:"dropped,omitempty"`
+	Spilled  int64 `json:"spilled,omitempty"`
+}
+
+// QueueStats returns state of all queues between pipelines stages.
+func (ps *Pipelines) QueueStats() []QueueStats {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+
+	stats := make([]QueueStats, 0, len(ps.workers)*2+len(ps.writers))
+
+	for _, w := range ps.workers {
+		stats = append(stats, QueueStats{Name: "in/" + w.name, Depth: len(w.in), Capacity: cap(w.in)})
+
+		for _, o := range w.outputs() {
+			stats = append(stats, QueueStats{Name: "out/" + o.name, Depth: len(o.data), Capacity: cap(o.data)})
+		}
+	}
+
+	for _, w := range ps.writers {
+		for _, sw := range w.sinks {
+			depth, dropped, spilled := sw.q.stats()
+
+			stats = append(stats, QueueStats{
+				Name:     "sink/" + outputKey(sw.name, w.name),
+				Depth:    depth,
+				Capacity: sw.q.size,
+				Dropped:  dropped,
+				Spilled:  spilled,
+			})
+		}
+	}
+
+	return stats
+}
@@ -0,0 +1,352 @@
+package pipelines
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/candles/pipelines/candles"
+)
+
+var (
+	ErrPriceNotPositive = errors.New("price is not positive")
+	ErrCountNotPositive = errors.New("count is not positive")
+	ErrPriceJump        = errors.New("price jump exceeds limit")
+	ErrTimeOutOfBounds  = errors.New("timestamp is out of bounds")
+	ErrDuplicateTrade   = errors.New("duplicate trade")
+)
+
+var errRuleNotInCheckpoint = errors.New("validation rule is not present in checkpoint")
+
+// Rule is a data quality check of trades applied before they reach pipelines.
+// Some rules keep state of previous trades, so rules must not be shared between aggregators.
+// State of rules is saved to checkpoints by rule names.
+type Rule struct {
+	name  string
+	check func(tr candles.Trade) error
+	// accept updates state of rule with trade passed all rules, it is optional.
+	accept func(tr candles.Trade)
+	// save and restore marshal state of rule to JSON, they are set for stateful rules only.
+	save    func() ([]byte, error)
+	restore func(data []byte) error
+}
+
+// PositivePrice rejects trades with zero or negative price.
+func PositivePrice() Rule {
+	return Rule{
+		name: "positive_price",
+		check: func(tr candles.Trade) error {
+			if tr.Price() <= 0 {
+				return ErrPriceNotPositive
+			}
+
+			return nil
+		},
+	}
+}
+
+// PositiveCount rejects trades with zero or negative count.
+func PositiveCount() Rule {
+	return Rule{
+		name: "positive_count",
+		check: func(tr candles.Trade) error {
+			if tr.Count() <= 0 {
+				return ErrCountNotPositive
+			}
+
+			return nil
+		},
+	}
+}
+
+// MaxPriceJump rejects trades which price differs from price of the last accepted trade
+// of the same ticker by more than ratio of it, e.g. 0.1 allows 10% jumps.
+func MaxPriceJump(ratio float64) Rule {
+	last := make(map[string]float64)
+
+	return Rule{
+		name: "max_price_jump",
+		check: func(tr candles.Trade) error {
+			prev, ok := last[tr.Ticker()]
+			if !ok || prev == 0 {
+				return nil
+			}
+
+			if math.Abs(tr.Price()-prev) > ratio*math.Abs(prev) {
+				return ErrPriceJump
+			}
+
+			return nil
+		},
+		accept: func(tr candles.Trade) {
+			last[tr.Ticker()] = tr.Price()
+		},
+		save: func() ([]byte, error) {
+			return json.Marshal(last)
+		},
+		restore: func(data []byte) error {
+			return json.Unmarshal(data, &last)
+		},
+	}
+}
+
+// TimeBounds rejects trades before notBefore or more than maxAhead later than current time.
+// Zero notBefore disables lower bound, negative maxAhead disables upper one.
+func TimeBounds(notBefore time.Time, maxAhead time.Duration) Rule {
+	return Rule{
+		name: "time_bounds",
+		check: func(tr candles.Trade) error {
+			if !notBefore.IsZero() && tr.Timestamp.Before(notBefore) {
+				return ErrTimeOutOfBounds
+			}
+
+			if maxAhead >= 0 && tr.Timestamp.After(time.Now().Add(maxAhead)) {
+				return ErrTimeOutOfBounds
+			}
+
+			return nil
+		},
+	}
+}
+
+// tradeKey identifies trade of a ticker.
+type tradeKey struct {
+	Price float64      `json:"price"`
+	Count int          `json:"count"`
+	Side  candles.Side `json:"side,omitempty"`
+	TS    int64        `json:"ts"`
+}
+
+// tickerTrades contains trades of a ticker accepted within window before its latest trade.
+type tickerTrades struct {
+	latest time.Time
+	seen   map[tradeKey]struct{}
+	// order contains keys of seen in the order they are accepted, so old keys are removed first.
+	order []tradeKey
+}
+
+// expire removes trades before cutoff.
+// Trades accepted out of order are removed when all trades accepted before them are.
+func (tt *tickerTrades) expire(cutoff int64) {
+	for len(tt.order) > 0 && tt.order[0].TS < cutoff {
+		delete(tt.seen, tt.order[0])
+		tt.order = tt.order[1:]
+	}
+}
+
+// tickerTradesJSON is a JSON representation of tickerTrades.
+type tickerTradesJSON struct {
+	Latest time.Time  `json:"timestamp"`
+	Trades []tradeKey `json:"trades"`
+}
+
+// NoDuplicates rejects trades equal to trades of the same ticker accepted
+// not earlier than window before the latest accepted trade of the ticker,
+// so trades replayed after newer ones are rejected too.
+// Duplicates of trades before the window are not detected.
+func NoDuplicates(window time.Duration) Rule {
+	last := make(map[string]*tickerTrades)
+
+	key := func(tr candles.Trade) tradeKey {
+		return tradeKey{Price: tr.Price(), Count: tr.Count(), Side: tr.Side(), TS: tr.Timestamp.UnixNano()}
+	}
+
+	return Rule{
+		name: "no_duplicates",
+		check: func(tr candles.Trade) error {
+			tt, ok := last[tr.Ticker()]
+			if !ok {
+				return nil
+			}
+
+			if _, ok = tt.seen[key(tr)]; ok {
+				return ErrDuplicateTrade
+			}
+
+			return nil
+		},
+		accept: func(tr candles.Trade) {
+			tt, ok := last[tr.Ticker()]
+			if !ok {
+				tt = &tickerTrades{seen: make(map[tradeKey]struct{})}
+				last[tr.Ticker()] = tt
+			}
+
+			if tr.Timestamp.After(tt.latest) {
+				tt.latest = tr.Timestamp
+			}
+
+			cutoff := tt.latest.Add(-window)
+			if tr.Timestamp.Before(cutoff) {
+				return
+			}
+
+			k := key(tr)
+			tt.seen[k] = struct{}{}
+			tt.order = append(tt.order, k)
+
+			tt.expire(cutoff.UnixNano())
+		},
+		save: func() ([]byte, error) {
+			v := make(map[string]tickerTradesJSON, len(last))
+
+			for t, tt := range last {
+				v[t] = tickerTradesJSON{Latest: tt.latest, Trades: tt.order}
+			}
+
+			return json.Marshal(v)
+		},
+		restore: func(data []byte) error {
+			var v map[string]tickerTradesJSON
+			if err := json.Unmarshal(data, &v); err != nil {
+				return err
+			}
+
+			for t, tj := range v {
+				tt := &tickerTrades{latest: tj.Latest, seen: make(map[tradeKey]struct{}, len(tj.Trades))}
+				for _, k := range tj.Trades {
+					tt.seen[k] = struct{}{}
+					tt.order = append(tt.order, k)
+				}
+
+				last[t] = tt
+			}
+
+			return nil
+		},
+	}
+}
+
+// RuleStats describes trades rejected by a validation rule.
+type RuleStats struct {
+	Rule     string `json:"rule"`
+	Rejected int64  `json:"rejected"`
+}
+
+// validator applies rules to trades and counts trades rejected by each of them.
+type validator struct {
+	rules    []Rule
+	rejected []int64
+}
+
+// validate returns name and error of the first rule trade doesn't pass.
+func (v *validator) validate(tr candles.Trade) (string, error) {
+	for i, r := range v.rules {
+		if err := r.check(tr); err != nil {
+			atomic.AddInt64(&v.rejected[i], 1)
+			return r.name, err
+		}
+	}
+
+	for _, r := range v.rules {
+		if r.accept != nil {
+			r.accept(tr)
+		}
+	}
+
+	return "", nil
+}
+
+// state returns JSON state of stateful rules by their names.
+func (v *validator) state() (map[string]json.RawMessage, error) {
+	st := make(map[string]json.RawMessage)
+
+	for _, r := range v.rules {
+		if r.save == nil {
+			continue
+		}
+
+		data, err := r.save()
+		if err != nil {
+			return nil, err
+		}
+
+		st[r.name] = data
+	}
+
+	return st, nil
+}
+
+// restoreState restores state of stateful rules from state returned by validator.state.
+func (v *validator) restoreState(st map[string]json.RawMessage) error {
+	for _, r := range v.rules {
+		if r.restore == nil {
+			continue
+		}
+
+		data, ok := st[r.name]
+		if !ok {
+			return errRuleNotInCheckpoint
+		}
+
+		if err := r.restore(data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SetRules sets rules trades are validated with in order before they reach pipelines.
+// Trade is rejected by the first rule it doesn't pass.
+// Must be called before Resume and Init.
+func (ps *Pipelines) SetRules(rules ...Rule) {
+	if len(rules) == 0 {
+		ps.validator = nil
+		return
+	}
+
+	ps.validator = &validator{rules: rules, rejected: make([]int64, len(rules))}
+}
+
+// RuleStats returns counts of trades rejected by each validation rule.
+func (ps *Pipelines) RuleStats() []RuleStats {
+	if ps.validator == nil {
+		return nil
+	}
+
+	stats := make([]RuleStats, 0, len(ps.validator.rules))
+
+	for i, r := range ps.validator.rules {
+		stats = append(stats, RuleStats{Rule: r.name, Rejected: atomic.LoadInt64(&ps.validator.rejected[i])})
+	}
+
+	return stats
+}
+
+// validateBatch removes trades rejected by validation rules from batch,
+// adding them to rejected lines.
+func (ps *Pipelines) validateBatch(b parsedBatch) parsedBatch {
+	trades := b.trades[:0]
+
+	for i, tr := range b.trades {
+		rule, err := ps.validator.validate(tr)
+		if err == nil {
+			trades = append(trades, tr)
+			continue
+		}
+
+		r := rejectedLine{Source: b.source, Reason: rule, Error: err.Error(), Data: string(b.raw[i])}
+		if b.lines != nil {
+			r.Line = b.lines[i]
+		}
+
+		ps.l.Errorf("invalid trade at %s: %s, %v", r.position(), r.Data, err)
+
+		b.rejects = append(b.rejects, r)
+	}
+
+	b.trades = trades
+
+	// parse errors and validation ones are written in the order of lines.
+	if b.lines != nil {
+		sort.SliceStable(b.rejects, func(i, j int) bool {
+			return b.rejects[i].Line < b.rejects[j].Line
+		})
+	}
+
+	return b
+}
@@ -0,0 +1,252 @@
+package pipelines
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/candles/files"
+	"github.com/candles/pipelines/candles"
+)
+
+func TestPipelines_Internal_validateBatch(t *testing.T) {
+	notBefore := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		rules       []Rule
+		lines       []string
+		want        []string
+		wantRejects []rejectedLine
+		wantStats   []RuleStats
+	}{
+		{
+			name:  "positive price and count",
+			rules: []Rule{PositivePrice(), PositiveCount()},
+			lines: []string{
+				"A,10,10,2019-01-30 11:00:00",
+				"A,-10,10,2019-01-30 11:00:01",
+				"A,0,-1,2019-01-30 11:00:02",
+				"A,10,-1,2019-01-30 11:00:03",
+			},
+			want: []string{"A,10,10,2019-01-30 11:00:00"},
+			wantRejects: []rejectedLine{
+				{Source: "trades.csv", Line: 2, Reason: "positive_price", Error: ErrPriceNotPositive.Error(), Data: "A,-10,10,2019-01-30 11:00:01"},
+				{Source: "trades.csv", Line: 3, Reason: "positive_price", Error: ErrPriceNotPositive.Error(), Data: "A,0,-1,2019-01-30 11:00:02"},
+				{Source: "trades.csv", Line: 4, Reason: "positive_count", Error: ErrCountNotPositive.Error(), Data: "A,10,-1,2019-01-30 11:00:03"},
+			},
+			wantStats: []RuleStats{{Rule: "positive_price", Rejected: 2}, {Rule: "positive_count", Rejected: 1}},
+		},
+		{
+			name:  "price jump per ticker",
+			rules: []Rule{MaxPriceJump(0.1)},
+			lines: []string{
+				"A,100,1,2019-01-30 11:00:00",
+				"B,1000,1,2019-01-30 11:00:00",
+				"A,1000,1,2019-01-30 11:00:01",
+				"A,109,1,2019-01-30 11:00:02",
+				"A,98.5,1,2019-01-30 11:00:03",
+			},
+			want: []string{
+				"A,100,1,2019-01-30 11:00:00",
+				"B,1000,1,2019-01-30 11:00:00",
+				"A,109,1,2019-01-30 11:00:02",
+				"A,98.5,1,2019-01-30 11:00:03",
+			},
+			wantRejects: []rejectedLine{
+				{Source: "trades.csv", Line: 3, Reason: "max_price_jump", Error: ErrPriceJump.Error(), Data: "A,1000,1,2019-01-30 11:00:01"},
+			},
+			wantStats: []RuleStats{{Rule: "max_price_jump", Rejected: 1}},
+		},
+		{
+			name:  "time bounds",
+			rules: []Rule{TimeBounds(notBefore, time.Hour)},
+			lines: []string{
+				"A,10,1,2018-12-31 23:59:59",
+				"A,10,1,2019-01-30 11:00:00",
+				"A,10,1,2999-01-30 11:00:00",
+			},
+			want: []string{"A,10,1,2019-01-30 11:00:00"},
+			wantRejects: []rejectedLine{
+				{Source: "trades.csv", Line: 1, Reason: "time_bounds", Error: ErrTimeOutOfBounds.Error(), Data: "A,10,1,2018-12-31 23:59:59"},
+				{Source: "trades.csv", Line: 3, Reason: "time_bounds", Error: ErrTimeOutOfBounds.Error(), Data: "A,10,1,2999-01-30 11:00:00"},
+			},
+			wantStats: []RuleStats{{Rule: "time_bounds", Rejected: 2}},
+		},
+		{
+			name:  "duplicates",
+			rules: []Rule{NoDuplicates(0)},
+			lines: []string{
+				"A,10,1,2019-01-30 11:00:00",
+				"B,10,1,2019-01-30 11:00:00",
+				"A,10,1,2019-01-30 11:00:00",
+				"A,10,2,2019-01-30 11:00:00",
+				"A,10,1,2019-01-30 11:00:01",
+			},
+			want: []string{
+				"A,10,1,2019-01-30 11:00:00",
+				"B,10,1,2019-01-30 11:00:00",
+				"A,10,2,2019-01-30 11:00:00",
+				"A,10,1,2019-01-30 11:00:01",
+			},
+			wantRejects: []rejectedLine{
+				{Source: "trades.csv", Line: 3, Reason: "no_duplicates", Error: ErrDuplicateTrade.Error(), Data: "A,10,1,2019-01-30 11:00:00"},
+			},
+			wantStats: []RuleStats{{Rule: "no_duplicates", Rejected: 1}},
+		},
+		{
+			name:  "duplicates after newer trades",
+			rules: []Rule{NoDuplicates(time.Minute)},
+			lines: []string{
+				"A,10,1,2019-01-30 11:00:00",
+				"A,11,1,2019-01-30 11:00:30",
+				"A,10,1,2019-01-30 11:00:00",
+				"A,12,1,2019-01-30 11:01:30",
+				"A,11,1,2019-01-30 11:00:30",
+				"A,10,1,2019-01-30 11:00:00",
+			},
+			want: []string{
+				"A,10,1,2019-01-30 11:00:00",
+				"A,11,1,2019-01-30 11:00:30",
+				"A,12,1,2019-01-30 11:01:30",
+				"A,10,1,2019-01-30 11:00:00",
+			},
+			wantRejects: []rejectedLine{
+				{Source: "trades.csv", Line: 3, Reason: "no_duplicates", Error: ErrDuplicateTrade.Error(), Data: "A,10,1,2019-01-30 11:00:00"},
+				{Source: "trades.csv", Line: 5, Reason: "no_duplicates", Error: ErrDuplicateTrade.Error(), Data: "A,11,1,2019-01-30 11:00:30"},
+			},
+			wantStats: []RuleStats{{Rule: "no_duplicates", Rejected: 2}},
+		},
+		{
+			name:  "original lines are quarantined",
+			rules: []Rule{TimeBounds(notBefore, -1)},
+			lines: []string{
+				" A,10.50,01,2018-12-31 23:59:59.123456789,buy ",
+				"A,10,1,2019-01-30 11:00:00",
+			},
+			want: []string{"A,10,1,2019-01-30 11:00:00"},
+			wantRejects: []rejectedLine{
+				{
+					Source: "trades.csv", Line: 1, Reason: "time_bounds", Error: ErrTimeOutOfBounds.Error(),
+					Data: " A,10.50,01,2018-12-31 23:59:59.123456789,buy ",
+				},
+			},
+			wantStats: []RuleStats{{Rule: "time_bounds", Rejected: 1}},
+		},
+		{
+			name:  "rejected by parser and rules in order of lines",
+			rules: []Rule{PositivePrice()},
+			lines: []string{
+				"A,-10,1,2019-01-30 11:00:00",
+				"invalid",
+				"A,0,1,2019-01-30 11:00:01",
+			},
+			want: []string{},
+			wantRejects: []rejectedLine{
+				{Source: "trades.csv", Line: 1, Reason: "positive_price", Error: ErrPriceNotPositive.Error(), Data: "A,-10,1,2019-01-30 11:00:00"},
+				{Source: "trades.csv", Line: 2, Reason: "invalid_value", Error: candles.ErrInvalidValue.Error(), Data: "invalid"},
+				{Source: "trades.csv", Line: 3, Reason: "positive_price", Error: ErrPriceNotPositive.Error(), Data: "A,0,1,2019-01-30 11:00:01"},
+			},
+			wantStats: []RuleStats{{Rule: "positive_price", Rejected: 2}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ps := New(readerMock{}, WriterBuilder{}, logrus.New())
+			ps.SetRules(test.rules...)
+
+			b := files.Batch{Source: "trades.csv", Line: 1}
+			for _, l := range test.lines {
+				b.Lines = append(b.Lines, []byte(l))
+			}
+
+			pb := ps.validateBatch(ps.parseBatch(candles.NewParser(), b))
+
+			got := make([]string, 0, len(pb.trades))
+			for _, tr := range pb.trades {
+				got = append(got, tr.String())
+			}
+
+			assert.Equal(t, test.want, got)
+			assert.Equal(t, test.wantRejects, pb.rejects)
+			assert.Equal(t, test.wantStats, ps.RuleStats())
+		})
+	}
+}
+
+func TestPipelines_Internal_validationResume(t *testing.T) {
+	dir, err := ioutil.TempDir("", "validation")
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	const (
+		before = "A,100,1,2019-01-30 11:00:00\n" +
+			"B,50,1,2019-01-30 11:00:00\n"
+		after = "A,100,1,2019-01-30 11:00:00\n" +
+			"A,200,1,2019-01-30 11:00:01\n" +
+			"B,50,1,2019-01-30 11:00:01\n" +
+			"A,105,1,2019-01-30 11:00:02\n"
+	)
+
+	tradesPath := filepath.Join(dir, "trades.csv")
+	assert.NoError(t, ioutil.WriteFile(tradesPath, []byte(before+after), 0600))
+
+	rules := func() []Rule {
+		return []Rule{NoDuplicates(time.Minute), MaxPriceJump(0.1)}
+	}
+
+	batch := func(data string, line int64) files.Batch {
+		b := files.Batch{Source: "trades.csv", Line: line}
+		for _, l := range strings.Split(strings.TrimSuffix(data, "\n"), "\n") {
+			b.Lines = append(b.Lines, []byte(l))
+		}
+
+		return b
+	}
+
+	// uninterrupted run.
+	ps := New(readerMock{}, WriterBuilder{}, logrus.New())
+	ps.SetRules(rules()...)
+
+	ps.validateBatch(ps.parseBatch(candles.NewParser(), batch(before, 1)))
+	want := ps.validateBatch(ps.parseBatch(candles.NewParser(), batch(after, 3)))
+
+	assert.Len(t, want.trades, 2)
+	assert.Len(t, want.rejects, 2)
+
+	// interrupted run.
+	cpPath := filepath.Join(dir, "checkpoint.json")
+
+	ps = New(readerMock{}, WriterBuilder{}, logrus.New())
+	ps.SetRules(rules()...)
+	ps.EnableCheckpoints(cpPath, 0)
+
+	ps.validateBatch(ps.parseBatch(candles.NewParser(), batch(before, 1)))
+	assert.NoError(t, ps.checkpoint(int64(len(before))))
+
+	r, err := files.NewReader(tradesPath, logrus.New())
+	assert.NoError(t, err)
+
+	ps = New(r, WriterBuilder{}, logrus.New())
+	ps.SetRules(rules()...)
+	assert.NoError(t, ps.Resume(cpPath))
+
+	got := ps.validateBatch(ps.parseBatch(candles.NewParser(), batch(after, 3)))
+	assert.Equal(t, want.trades, got.trades)
+	assert.Equal(t, want.rejects, got.rejects)
+
+	// checkpoint saved without validation rules.
+	assert.NoError(t, saveCheckpoint(cpPath, checkpoint{}))
+
+	ps = New(r, WriterBuilder{}, logrus.New())
+	ps.SetRules(rules()...)
+	assert.Equal(t, errRuleNotInCheckpoint, ps.Resume(cpPath))
+}
@@ -1,7 +1,7 @@
 package pipelines
 
 import (
-	"strings"
+	"encoding/json"
 	"time"
 
 	"github.com/candles/pipelines/candles"
@@ -10,21 +10,67 @@ import (
 // Worker describes single pipeline with provided time interval.
 type Worker struct {
 	interval int
+	name     string
 	in       chan candles.Trade
-	out      chan string
+	out      chan Batch
+	ctl      chan func()
+	cs       *candles.Storage
+	// bars builds bars closed by trading activity instead of time intervals.
+	bars     *candles.Bars
+	barKind  candles.BarKind
+	barSize  float64
+	barSizes map[string]float64
 
+	// session is the working range intervals start at.
+	session       session
 	intervalD     time.Duration
 	intervalStart time.Time
 	intervalEnd   time.Time
+	// since is the time trades before which are already present in output.
+	since time.Time
+	// appendedUntil is the time of the last trade of appended output,
+	// trades at or before it are already present in output.
+	appendedUntil time.Time
+	// appendPath is the path of appended output, lastCandles are the candles
+	// of its last interval, which state is saved to continue output again.
+	appendPath  string
+	lastCandles []candles.Candle
+	// alignStart makes worker skip trades until the start of the next interval,
+	// so the first candles of pipeline added at runtime aren't missing trades.
+	alignStart bool
+	// columns returns additional output columns of candle.
+	columns []func(c *candles.Candle) string
+	// ha transforms output candles into Heikin-Ashi candles sent to haOut.
+	ha    *candles.HeikinAshi
+	haOut chan Batch
+	// ind computes indicators columns of output candles.
+	ind      *candles.Indicators
+	indSpecs []candles.IndicatorSpec
+	// handler is called with candles of each output after they are output.
+	handler func(name string, cs []candles.Candle)
+	// partialEvery is the interval between emissions of not closed candles to partialOut.
+	partialEvery time.Duration
+	partialOut   chan Batch
+	// done is closed when worker stops.
+	done chan struct{}
+}
+
+// output describes named output of worker.
+type output struct {
+	name string
+	data <-chan Batch
 }
 
 // NewWorker creates new pipeline worker with provided interval.
 func NewWorker(interval int) *Worker {
 	return &Worker{
 		interval:  interval,
+		session:   defaultSession,
 		intervalD: time.Minute * time.Duration(interval),
 		in:        make(chan candles.Trade),
-		out:       make(chan string),
+		out:       make(chan Batch),
+		ctl:       make(chan func()),
+		done:      make(chan struct{}),
 	}
 }
 
@@ -36,9 +82,9 @@ func (w *Worker) incrementInterval(trTime time.Time) {
 
 	for trTime.After(w.intervalEnd) || trTime.Equal(w.intervalEnd) {
 		newStart := w.intervalStart.Add(w.intervalD)
-		if (w.intervalStart == time.Time{} || !inWorkingRange(newStart)) {
-			// set start interval for 10:00 of current day if not already set.
-			w.intervalStart = trTime.Truncate(time.Hour * hoursInDay).Add(time.Hour * time.Duration(startHours))
+		if (w.intervalStart == time.Time{} || !w.session.contains(newStart)) {
+			// set start interval for session start of current day if not already set.
+			w.intervalStart = trTime.Truncate(time.Hour * hoursInDay).Add(time.Hour * time.Duration(w.session.start))
 		} else {
 			w.intervalStart = newStart
 		}
@@ -50,20 +96,217 @@ func (w *Worker) incrementInterval(trTime time.Time) {
 // start starts worker, that listens to in-channel,
 // collects candles from trades, handles auto-flush to file,
 // when time-interval exceeds.
+// Functions received from control chan are executed after all trades buffered before them.
 func (w *Worker) start() {
-	cs := candles.NewStorage()
+	if w.cs == nil {
+		w.cs = candles.NewStorage()
+	}
+
+	if w.done != nil {
+		defer close(w.done)
+	}
+
+	var partial <-chan time.Time
+
+	if w.partialEvery > 0 {
+		t := time.NewTicker(w.partialEvery)
+		defer t.Stop()
+
+		partial = t.C
+	}
+
+	for {
+		select {
+		case tr, ok := <-w.in:
+			if !ok {
+				if w.appendPath != "" {
+					w.lastCandles = w.cs.Candles()
+				}
+
+				w.flush(w.cs)
+				w.flushBars()
+				close(w.out)
+
+				if w.haOut != nil {
+					close(w.haOut)
+				}
+
+				if w.partialOut != nil {
+					close(w.partialOut)
+				}
+
+				return
+			}
+
+			w.addTrade(tr)
+		case <-partial:
+			w.emitPartial()
+		case f := <-w.ctl:
+			// trades dispatched before f are processed first.
+			for n := len(w.in); n > 0; n-- {
+				w.addTrade(<-w.in)
+			}
+
+			f()
+		}
+	}
+}
+
+// addTrade adds trade to candles, sending closed candles to output.
+func (w *Worker) addTrade(tr candles.Trade) {
+	if w.bars != nil {
+		w.addBarTrade(tr)
+		return
+	}
+
+	if w.alignStart {
+		w.alignStart = false
+		w.alignTo(tr.Timestamp)
+	}
+
+	if tr.Timestamp.Before(w.since) || !tr.Timestamp.After(w.appendedUntil) {
+		return
+	}
+
+	if tr.Timestamp.After(w.intervalEnd) || tr.Timestamp.Equal(w.intervalEnd) {
+		w.flush(w.cs)
+		w.incrementInterval(tr.Timestamp)
+	}
+
+	w.cs.AddTrade(tr, w.intervalStart)
+}
+
+// alignTo makes worker skip trades until the start of the interval following t,
+// unless t is the interval start.
+// Does nothing if worker continues existing output.
+func (w *Worker) alignTo(t time.Time) {
+	if !w.intervalStart.IsZero() {
+		return
+	}
+
+	w.incrementInterval(t)
+
+	if t.After(w.intervalStart) {
+		w.since = w.intervalEnd
+	}
+}
+
+// do executes f inside worker goroutine and waits for it to complete.
+// Reports false without executing f if worker is already stopped.
+func (w *Worker) do(f func()) bool {
+	done := make(chan struct{})
+
+	select {
+	case w.ctl <- func() {
+		f()
+		close(done)
+	}:
+	case <-w.done:
+		return false
+	}
+
+	<-done
+
+	return true
+}
+
+// state returns worker state to be persisted in checkpoint.
+// Has to be called inside worker goroutine.
+func (w *Worker) state() (workerState, error) {
+	st := workerState{
+		IntervalStart: w.intervalStart,
+		IntervalEnd:   w.intervalEnd,
+		Candles:       w.cs.Candles(),
+	}
+
+	if w.bars != nil {
+		// bars are marshaled right away, as they are changed by next trades.
+		data, err := json.Marshal(w.bars)
+		if err != nil {
+			return workerState{}, err
+		}
+
+		st.Bars = data
+	}
+
+	if w.ha != nil {
+		data, err := json.Marshal(w.ha)
+		if err != nil {
+			return workerState{}, err
+		}
+
+		st.HeikinAshi = data
+	}
 
-	for tr := range w.in {
-		if tr.Timestamp.After(w.intervalEnd) || tr.Timestamp.Equal(w.intervalEnd) {
-			w.flush(cs)
-			w.incrementInterval(tr.Timestamp)
+	if w.ind != nil {
+		data, err := json.Marshal(w.ind)
+		if err != nil {
+			return workerState{}, err
 		}
 
-		cs.AddTrade(tr, w.intervalStart)
+		st.Indicators = data
 	}
 
-	w.flush(cs)
-	close(w.out)
+	return st, nil
+}
+
+// restore restores worker state from checkpoint.
+// Has to be called before worker start.
+func (w *Worker) restore(st workerState) error {
+	w.intervalStart = st.IntervalStart
+	w.intervalEnd = st.IntervalEnd
+	w.cs = candles.NewStorage()
+
+	for _, c := range st.Candles {
+		w.cs.Put(c)
+	}
+
+	if w.bars != nil && len(st.Bars) > 0 {
+		if err := json.Unmarshal(st.Bars, w.bars); err != nil {
+			return err
+		}
+	}
+
+	if w.ha != nil && len(st.HeikinAshi) > 0 {
+		if err := json.Unmarshal(st.HeikinAshi, w.ha); err != nil {
+			return err
+		}
+	}
+
+	if w.ind != nil && len(st.Indicators) > 0 {
+		return json.Unmarshal(st.Indicators, w.ind)
+	}
+
+	return nil
+}
+
+// continueFrom makes worker continue the interval of candles
+// read from existing output, skipping trades before it
+// and trades at or before the latest close trade of candles.
+// If close trade time of any candle is unknown, trades of the whole interval are skipped,
+// as trades already present in output can't be told from new ones.
+// Has to be called before worker start.
+func (w *Worker) continueFrom(cs []candles.Candle) {
+	if len(cs) == 0 {
+		return
+	}
+
+	w.intervalStart = cs[0].StartTime()
+	w.intervalEnd = w.intervalStart.Add(w.intervalD)
+	w.since = w.intervalStart
+	w.cs = candles.NewStorage()
+
+	for _, c := range cs {
+		w.cs.Put(c)
+
+		if c.CloseTime().IsZero() {
+			w.since = w.intervalEnd
+		}
+
+		if c.CloseTime().After(w.appendedUntil) {
+			w.appendedUntil = c.CloseTime()
+		}
+	}
 }
 
 // flush flushes all data from storage to file writer.
@@ -73,15 +316,153 @@ func (w *Worker) flush(cs *candles.Storage) {
 		return
 	}
 
-	c := cs.Candles()
-	data := make([]string, 0, len(c))
+	w.emit(cs.Candles())
+	cs.Clear()
+}
+
+// addBarTrade adds trade to bars and sends the bar to output if it is closed.
+func (w *Worker) addBarTrade(tr candles.Trade) {
+	c := w.bars.AddTrade(tr)
+	if len(c) == 0 {
+		return
+	}
+
+	w.emit(c)
+}
+
+// flushBars flushes all not closed bars to file writer.
+// Does nothing if there are no bars.
+func (w *Worker) flushBars() {
+	if w.bars == nil {
+		return
+	}
+
+	c := w.bars.Candles()
+	if len(c) == 0 {
+		return
+	}
+
+	w.emit(c)
+}
+
+// openCandles returns candles of not closed intervals or bars.
+// Has to be called inside worker goroutine.
+func (w *Worker) openCandles() []candles.Candle {
+	if w.bars != nil {
+		return w.bars.Candles()
+	}
+
+	return w.cs.Candles()
+}
+
+// emitPartial sends candles of not closed intervals or bars to partial output.
+// Does nothing if there are no such candles.
+func (w *Worker) emitPartial() {
+	c := w.openCandles()
+	if len(c) == 0 {
+		return
+	}
+
+	b := w.batch("partial_"+w.name, c, w.partialColumns)
+	b.Partial = true
+
+	w.partialOut <- b
+
+	if w.handler != nil {
+		w.handler(b.Output, c)
+	}
+}
+
+// emit sends candles to output along with their indicators,
+// then sends their Heikin-Ashi candles to Heikin-Ashi output if it is enabled.
+func (w *Worker) emit(c []candles.Candle) {
+	w.out <- w.batch(w.name, c, w.closedColumns)
+
+	if w.handler != nil {
+		w.handler(w.name, c)
+	}
 
+	if w.ha == nil {
+		return
+	}
+
+	// candles sent to output may be still being written.
+	ha := make([]candles.Candle, 0, len(c))
 	for i := range c {
-		data = append(data, c[i].String())
+		ha = append(ha, w.ha.Transform(c[i]))
 	}
 
-	chunk := strings.Join(data, "\n")
-	w.out <- chunk
+	w.haOut <- w.batch("ha_"+w.name, ha, nil)
 
-	cs.Clear()
+	if w.handler != nil {
+		w.handler("ha_"+w.name, ha)
+	}
+}
+
+// closedColumns returns indicators columns of closed candle.
+func (w *Worker) closedColumns(c *candles.Candle) []string {
+	if w.ind == nil {
+		return nil
+	}
+
+	return []string{w.ind.Add(c)}
+}
+
+// partialColumns returns indicators columns of not closed candle.
+// Indicators are computed from closed candles only, so their values are empty.
+func (w *Worker) partialColumns(*candles.Candle) []string {
+	if w.ind == nil {
+		return nil
+	}
+
+	return []string{w.ind.Empty()}
+}
+
+// batch returns output batch of candles with their columns.
+// Values returned by extra are added to columns of each candle if extra is not nil.
+func (w *Worker) batch(name string, c []candles.Candle, extra func(c *candles.Candle) []string) Batch {
+	b := Batch{
+		Output:  name,
+		Candles: c,
+	}
+
+	// interval of bars pipeline is their rounded up size, not the time interval of candles.
+	if w.bars == nil {
+		b.Interval = w.interval
+	}
+
+	if len(w.columns) == 0 && extra == nil {
+		return b
+	}
+
+	b.Columns = make([][]string, 0, len(c))
+
+	for i := range c {
+		values := make([]string, 0, len(w.columns))
+		for _, col := range w.columns {
+			values = append(values, col(&c[i]))
+		}
+
+		if extra != nil {
+			values = append(values, extra(&c[i])...)
+		}
+
+		b.Columns = append(b.Columns, values)
+	}
+
+	return b
+}
+
+// outputs returns all outputs of worker.
+func (w *Worker) outputs() []output {
+	out := []output{{name: w.name, data: w.out}}
+	if w.ha != nil {
+		out = append(out, output{name: "ha_" + w.name, data: w.haOut})
+	}
+
+	if w.partialOut != nil {
+		out = append(out, output{name: "partial_" + w.name, data: w.partialOut})
+	}
+
+	return out
 }
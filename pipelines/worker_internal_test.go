@@ -1,7 +1,6 @@
 package pipelines
 
 import (
-	"strings"
 	"testing"
 	"time"
 
@@ -37,7 +36,7 @@ func TestWorker_Internal_flush(t *testing.T) {
 			name: "success, multiple values in storage",
 			args: args{
 				w: &Worker{
-					out: make(chan string, 1),
+					out: make(chan Batch, 1),
 				},
 				trades: []candles.Trade{
 					candles.MustTradeFromString("TICKER_ONE,200.000000,10,2019-01-30 06:59:45.000249"),
@@ -54,7 +53,7 @@ func TestWorker_Internal_flush(t *testing.T) {
 			name: "empty storage",
 			args: args{
 				w: &Worker{
-					out: make(chan string, 1),
+					out: make(chan Batch, 1),
 				},
 			},
 			wantOutput: false,
@@ -71,7 +70,7 @@ func TestWorker_Internal_flush(t *testing.T) {
 			test.args.w.flush(cs)
 			defer close(test.args.w.out)
 			var (
-				output    string
+				output    Batch
 				outExists bool
 			)
 			select {
@@ -80,10 +79,8 @@ func TestWorker_Internal_flush(t *testing.T) {
 			default:
 			}
 			outCheck := make(map[string]bool)
-			if output != "" {
-				for _, s := range strings.Split(output, "\n") {
-					outCheck[s] = true
-				}
+			for _, s := range output.Lines() {
+				outCheck[s] = true
 			}
 			assert.Equal(t, test.wantOutput, outExists)
 			assert.Equal(t, test.want, outCheck)
@@ -111,7 +108,7 @@ func TestWorker_Internal_start(t *testing.T) {
 			name: "success, multiple values in storage",
 			args: args{
 				w: &Worker{
-					out: make(chan string),
+					out: make(chan Batch),
 					in:  make(chan candles.Trade),
 
 					intervalStart: defaultTime,
@@ -138,7 +135,7 @@ func TestWorker_Internal_start(t *testing.T) {
 			}
 			close(test.args.w.in)
 			var (
-				output    string
+				output    Batch
 				outExists bool
 			)
 			select {
@@ -147,10 +144,8 @@ func TestWorker_Internal_start(t *testing.T) {
 			case <-time.NewTicker(time.Second * 2).C:
 			}
 			outCheck := make(map[string]bool)
-			if output != "" {
-				for _, s := range strings.Split(output, "\n") {
-					outCheck[s] = true
-				}
+			for _, s := range output.Lines() {
+				outCheck[s] = true
 			}
 			assert.Equal(t, test.wantOutput, outExists)
 			assert.Equal(t, test.want, outCheck)
@@ -225,3 +220,236 @@ func TestWorker_Internal_incrementInterval(t *testing.T) {
 		})
 	}
 }
+
+func TestWorker_Internal_batch(t *testing.T) {
+	defaultTime := mustParseTime("2019-01-30 11:00:00.000000")
+	c := candles.New(candles.MustTradeFromString("TICKER,200.000000,10,2019-01-30 11:00:45.000000,B"), defaultTime)
+
+	tests := []struct {
+		name string
+		opts []Option
+		want string
+	}{
+		{
+			name: "no options",
+			want: "TICKER,2019-01-30T11:00:00Z,200.000000,200.000000,200.000000,200.000000",
+		},
+		{
+			name: "order flow",
+			opts: []Option{WithOrderFlow()},
+			want: "TICKER,2019-01-30T11:00:00Z,200.000000,200.000000,200.000000,200.000000,10,0,10",
+		},
+		{
+			name: "order flow and trade times",
+			opts: []Option{WithOrderFlow(), WithTradeTimes()},
+			want: "TICKER,2019-01-30T11:00:00Z,200.000000,200.000000,200.000000,200.000000,10,0,10," +
+				"2019-01-30T11:00:45Z,2019-01-30T11:00:45Z,2019-01-30T11:00:45Z,2019-01-30T11:00:45Z",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := NewWorker(5)
+			for _, opt := range test.opts {
+				opt(w)
+			}
+
+			b := w.batch("candle_5min", []candles.Candle{*c}, nil)
+			assert.Equal(t, 5, b.Interval)
+			assert.Equal(t, test.want, b.String())
+		})
+	}
+}
+
+func TestWorker_Internal_startBars(t *testing.T) {
+	w := NewWorker(2)
+	WithBars(candles.TickBars)(w)
+	applyBars(w)
+
+	assert.Equal(t, "tick_2", w.name)
+
+	go w.start()
+
+	go func() {
+		for _, s := range []string{
+			"TICKER,100.000000,10,2019-01-30 11:00:01.000000",
+			"TICKER,200.000000,10,2019-01-30 11:00:02.000000",
+			"TICKER,300.000000,10,2019-01-30 11:00:03.000000",
+		} {
+			w.in <- candles.MustTradeFromString(s)
+		}
+
+		close(w.in)
+	}()
+
+	got := make([]string, 0, 2)
+	for s := range w.out {
+		assert.Equal(t, "tick_2", s.Output)
+		assert.Zero(t, s.Interval)
+
+		got = append(got, s.String())
+	}
+
+	assert.Equal(t, []string{
+		"TICKER,2019-01-30T11:00:01Z,100.000000,200.000000,100.000000,200.000000",
+		"TICKER,2019-01-30T11:00:03Z,300.000000,300.000000,300.000000,300.000000",
+	}, got)
+}
+
+func TestWorker_Internal_startHeikinAshi(t *testing.T) {
+	w := NewWorker(5)
+	w.name = "candle_5min"
+	WithHeikinAshi()(w)
+	applyHeikinAshi(w, 0)
+
+	outs := w.outputs()
+	assert.Len(t, outs, 2)
+	assert.Equal(t, "ha_candle_5min", outs[1].name)
+
+	go w.start()
+
+	go func() {
+		for _, s := range []string{
+			"TICKER,100.000000,10,2019-01-30 11:00:01.000000",
+			"TICKER,200.000000,10,2019-01-30 11:00:02.000000",
+			"TICKER,300.000000,10,2019-01-30 11:05:01.000000",
+		} {
+			w.in <- candles.MustTradeFromString(s)
+		}
+
+		close(w.in)
+	}()
+
+	var got, gotHA []string
+
+	for out, ha := w.out, w.haOut; out != nil || ha != nil; {
+		select {
+		case s, ok := <-out:
+			if !ok {
+				out = nil
+				continue
+			}
+
+			got = append(got, s.String())
+		case s, ok := <-ha:
+			if !ok {
+				ha = nil
+				continue
+			}
+
+			gotHA = append(gotHA, s.String())
+		}
+	}
+
+	assert.Equal(t, []string{
+		"TICKER,2019-01-30T11:00:00Z,100.000000,200.000000,100.000000,200.000000",
+		"TICKER,2019-01-30T11:05:00Z,300.000000,300.000000,300.000000,300.000000",
+	}, got)
+	assert.Equal(t, []string{
+		"TICKER,2019-01-30T11:00:00Z,150.000000,200.000000,100.000000,150.000000",
+		"TICKER,2019-01-30T11:05:00Z,150.000000,300.000000,150.000000,300.000000",
+	}, gotHA)
+}
+
+func TestWorker_Internal_startIndicators(t *testing.T) {
+	w := NewWorker(5)
+	WithIndicators(candles.IndicatorSpec{Kind: candles.SMA, Params: []float64{2}})(w)
+	assert.NoError(t, applyIndicators(w))
+
+	go w.start()
+
+	go func() {
+		for _, s := range []string{
+			"TICKER,100.000000,10,2019-01-30 11:00:01.000000",
+			"TICKER,200.000000,10,2019-01-30 11:05:01.000000",
+		} {
+			w.in <- candles.MustTradeFromString(s)
+		}
+
+		close(w.in)
+	}()
+
+	got := make([]string, 0, 2)
+	for s := range w.out {
+		got = append(got, s.String())
+	}
+
+	assert.Equal(t, []string{
+		"TICKER,2019-01-30T11:00:00Z,100.000000,100.000000,100.000000,100.000000,",
+		"TICKER,2019-01-30T11:05:00Z,200.000000,200.000000,200.000000,200.000000,150.000000",
+	}, got)
+}
+
+func TestWorker_Internal_startPartialCandles(t *testing.T) {
+	w := NewWorker(5)
+	w.name = "candle_5min"
+	WithPartialCandles(time.Millisecond)(w)
+	applyPartialCandles(w, 0)
+
+	assert.Equal(t, []string{"candle_5min", "partial_candle_5min"}, outputNames(w))
+
+	go w.start()
+
+	w.in <- candles.MustTradeFromString("TICKER,100.000000,10,2019-01-30 11:00:01.000000")
+
+	b := <-w.partialOut
+	assert.True(t, b.Partial)
+	assert.Equal(t, "partial_candle_5min", b.Output)
+	assert.Equal(t, "TICKER,2019-01-30T11:00:00Z,100.000000,100.000000,100.000000,100.000000", b.String())
+
+	go func() {
+		for range w.partialOut {
+		}
+	}()
+
+	close(w.in)
+
+	var got []string
+	for b := range w.out {
+		assert.False(t, b.Partial)
+		got = append(got, b.String())
+	}
+
+	// candles output contains closed candles only.
+	assert.Equal(t, []string{"TICKER,2019-01-30T11:00:00Z,100.000000,100.000000,100.000000,100.000000"}, got)
+}
+
+// outputNames returns names of worker outputs.
+func outputNames(w *Worker) []string {
+	var names []string
+	for _, o := range w.outputs() {
+		names = append(names, o.name)
+	}
+
+	return names
+}
+
+func TestWorker_Internal_startCandlesHandler(t *testing.T) {
+	var got []string
+
+	w := NewWorker(5)
+	w.name = "candle_5min"
+	WithCandlesHandler(func(name string, cs []candles.Candle) {
+		for i := range cs {
+			got = append(got, name+":"+cs[i].String())
+		}
+	})(w)
+	WithHeikinAshi()(w)
+	applyHeikinAshi(w, 1)
+
+	go w.start()
+
+	go func() {
+		w.in <- candles.MustTradeFromString("TICKER,100.000000,10,2019-01-30 11:00:01.000000")
+		close(w.in)
+	}()
+
+	for range w.out {
+	}
+
+	<-w.done
+	assert.Equal(t, []string{
+		"candle_5min:TICKER,2019-01-30T11:00:00Z,100.000000,100.000000,100.000000,100.000000",
+		"ha_candle_5min:TICKER,2019-01-30T11:00:00Z,100.000000,100.000000,100.000000,100.000000",
+	}, got)
+}
@@ -1,6 +1,7 @@
 package pipelines
 
 import (
+	"path/filepath"
 	"sync"
 
 	"github.com/sirupsen/logrus"
@@ -8,45 +9,234 @@ import (
 	"github.com/candles/files"
 )
 
-// WriterBuilder returns factory of writers.
-type WriterBuilder struct{}
+// WriterBuilder returns factory of text file sinks.
+// Files are created in Dir, or in working directory if it is empty.
+type WriterBuilder struct {
+	Dir string
+}
+
+// New creates new TextSink writing to file of output with provided name.
+func (wb WriterBuilder) New(name string) (Sink, error) {
+	fw, err := files.NewWriter(wb.Path(name))
+	if err != nil {
+		return nil, err
+	}
+
+	return NewTextSink(fw), nil
+}
+
+// Open opens TextSink to existing file, discarding data after size bytes.
+func (wb WriterBuilder) Open(name string, size int64) (Sink, error) {
+	fw, err := files.OpenWriter(wb.Path(name), size)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewTextSink(fw), nil
+}
+
+// Path returns path of file of output with provided name.
+func (wb WriterBuilder) Path(name string) string {
+	return filepath.Join(wb.Dir, name)
+}
+
+// JSONWriterBuilder returns factory of JSON lines file sinks.
+// Files are named by outputs names with ".jsonl" extension
+// and created in Dir, or in working directory if it is empty.
+type JSONWriterBuilder struct {
+	Dir string
+}
+
+// New creates new JSON lines TextSink writing to file of output with provided name.
+func (wb JSONWriterBuilder) New(name string) (Sink, error) {
+	fw, err := files.NewWriter(wb.Path(name))
+	if err != nil {
+		return nil, err
+	}
+
+	return NewJSONSink(fw), nil
+}
+
+// Open opens JSON lines TextSink to existing file, discarding data after size bytes.
+func (wb JSONWriterBuilder) Open(name string, size int64) (Sink, error) {
+	fw, err := files.OpenWriter(wb.Path(name), size)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewJSONSink(fw), nil
+}
+
+// Path returns path of file of output with provided name.
+func (wb JSONWriterBuilder) Path(name string) string {
+	return filepath.Join(wb.Dir, name+jsonExt)
+}
+
+// jsonExt is the extension of JSON lines files.
+const jsonExt = ".jsonl"
+
+// TextSink writes batches to FileWriter as text lines.
+type TextSink struct {
+	fw     FileWriter
+	format func(b Batch) (string, error)
+}
+
+// NewTextSink creates new TextSink writing CSV lines.
+func NewTextSink(fw FileWriter) *TextSink {
+	return &TextSink{
+		fw: fw,
+		format: func(b Batch) (string, error) {
+			return b.String(), nil
+		},
+	}
+}
+
+// NewJSONSink creates new TextSink writing JSON lines.
+func NewJSONSink(fw FileWriter) *TextSink {
+	return &TextSink{
+		fw:     fw,
+		format: Batch.JSON,
+	}
+}
+
+// Write writes lines of batch candles.
+func (s *TextSink) Write(b Batch) error {
+	data, err := s.format(b)
+	if err != nil {
+		return err
+	}
 
-// New creates new Writer by given filepath.
-func (wb WriterBuilder) New(filepath string) (FileWriter, error) {
-	return files.NewWriter(filepath)
+	return s.fw.WriteString(data + "\n")
 }
 
-// Writer describes worker which writes data to corresponding file.
+// Sync commits written data and returns its size,
+// if FileWriter supports it.
+func (s *TextSink) Sync() (int64, error) {
+	sy, ok := s.fw.(syncer)
+	if !ok {
+		return 0, errCheckpointUnsupported
+	}
+
+	return sy.Sync()
+}
+
+// Close closes FileWriter.
+func (s *TextSink) Close() {
+	s.fw.Close()
+}
+
+// Writer describes worker which sends data of pipeline output to its sinks.
+// Each sink is written by its own goroutine from its own queue,
+// so a slow sink doesn't stall other ones.
 type Writer struct {
-	fw   FileWriter
-	data <-chan string
+	name  string
+	data  <-chan Batch
+	sinks []*sinkWriter
+	ctl   chan func()
+	// done is closed when all sinks are written and closed.
+	done chan struct{}
 
 	l *logrus.Logger
 }
 
-// NewWriter creates new Writer.
+// NewWriter creates new Writer to the main sink with default options.
 func NewWriter(
-	fw FileWriter,
-	data <-chan string,
+	s Sink,
+	data <-chan Batch,
 	l *logrus.Logger,
 ) *Writer {
-	return &Writer{
-		fw:   fw,
+	w := &Writer{
 		data: data,
+		ctl:  make(chan func()),
+		done: make(chan struct{}),
 		l:    l,
 	}
+
+	// default options never fail queue creation.
+	_ = w.addSink("", s, newSinkOptions())
+
+	return w
 }
 
+// addSink adds sink with provided name and options to writer.
+// Must be called before writer start.
+func (w *Writer) addSink(name string, s Sink, opts sinkOptions) error {
+	sw, err := newSinkWriter(name, s, opts, w.l)
+	if err != nil {
+		return err
+	}
+
+	w.sinks = append(w.sinks, sw)
+
+	return nil
+}
+
+// closeSinks closes sinks of not started writer.
+func (w *Writer) closeSinks() {
+	for _, sw := range w.sinks {
+		sw.s.Close()
+		sw.q.removeSpill()
+	}
+}
+
+// startWriting sends data to sinks until data chan is closed,
+// then waits for sinks to write queued data.
+// Functions received from control chan are executed after all data buffered before them is sent.
 func (w *Writer) startWriting(wg *sync.WaitGroup) {
-	defer w.fw.Close()
+	defer wg.Done()
 
-	for data := range w.data {
-		err := w.fw.WriteString(data + "\n")
-		if err != nil {
-			w.l.Errorf("error writing to file: %v", err)
-			continue
+	if w.done != nil {
+		defer close(w.done)
+	}
+
+	sinksWg := &sync.WaitGroup{}
+
+	for _, sw := range w.sinks {
+		sinksWg.Add(1)
+
+		go sw.start(sinksWg)
+	}
+
+	for {
+		select {
+		case data, ok := <-w.data:
+			if !ok {
+				for _, sw := range w.sinks {
+					sw.q.close()
+				}
+
+				sinksWg.Wait()
+
+				return
+			}
+
+			w.send(data)
+		case f := <-w.ctl:
+			for n := len(w.data); n > 0; n-- {
+				w.send(<-w.data)
+			}
+
+			f()
+		}
+	}
+}
+
+// send queues data for all sinks.
+func (w *Writer) send(data Batch) {
+	for _, sw := range w.sinks {
+		if err := sw.q.push(data); err != nil {
+			w.l.Errorf("can't queue %s for sink %q: %v", data.Output, sw.name, err)
 		}
 	}
+}
+
+// do executes f inside writer goroutine and waits for it to complete.
+func (w *Writer) do(f func()) {
+	done := make(chan struct{})
+	w.ctl <- func() {
+		f()
+		close(done)
+	}
 
-	wg.Done()
+	<-done
 }
@@ -6,6 +6,9 @@ import (
 	"testing"
 
 	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/candles/pipelines/candles"
 )
 
 func TestWorker_Internal_startWriting(t *testing.T) {
@@ -32,8 +35,8 @@ func TestWorker_Internal_startWriting(t *testing.T) {
 			name: "success, multiple values written",
 			args: args{
 				data: []string{
-					"stringOne",
-					"stringTwo",
+					"TICKER,2019-01-30T11:00:00Z,1.000000,2.000000,0.500000,1.500000",
+					"TICKER,2019-01-30T11:05:00Z,1.500000,1.500000,1.000000,1.000000",
 				},
 			},
 			setup: func(a args) {
@@ -46,8 +49,8 @@ func TestWorker_Internal_startWriting(t *testing.T) {
 			name: "multiple values written, error on write",
 			args: args{
 				data: []string{
-					"stringOne",
-					"stringTwo",
+					"TICKER,2019-01-30T11:00:00Z,1.000000,2.000000,0.500000,1.500000",
+					"TICKER,2019-01-30T11:05:00Z,1.500000,1.500000,1.000000,1.000000",
 				},
 			},
 			setup: func(a args) {
@@ -74,15 +77,18 @@ func TestWorker_Internal_startWriting(t *testing.T) {
 			test.setup(test.args)
 			defer writerMock.AssertExpectations(t)
 
-			input := make(chan string)
+			input := make(chan Batch)
 
-			w := NewWriter(writerMock, input, l)
+			w := NewWriter(NewTextSink(writerMock), input, l)
 			wg := &sync.WaitGroup{}
 			wg.Add(1)
 			go w.startWriting(wg)
 
 			for _, s := range test.args.data {
-				input <- s
+				c, err := candles.CandleFromString(s)
+				assert.NoError(t, err)
+
+				input <- Batch{Candles: []candles.Candle{c}}
 			}
 			close(input)
 			wg.Wait()
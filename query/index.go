@@ -0,0 +1,172 @@
+package query
+
+import (
+	"bufio"
+	"encoding/json"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"time"
+)
+
+// indexEvery is the minimal count of lines between index entries.
+const indexEvery = 256
+
+// indexSuffix is the suffix of index files names.
+const indexSuffix = ".idx"
+
+// checkSize is the size of data at the start and at the end of indexed data
+// which checksum is kept to detect rewritten files.
+const checkSize = 4096
+
+// index is a sparse index of candles file ordered by candle start time.
+// Entries point to the first lines of start times,
+// so all lines before entry offset start before entry time.
+type index struct {
+	// Size is the size of file data indexed.
+	Size    int64        `json:"size"`
+	Entries []indexEntry `json:"entries"`
+	// Last is the start time of the last indexed line.
+	Last time.Time `json:"last"`
+	// Lines is the count of lines indexed after the last entry.
+	Lines int `json:"lines"`
+	// Checksum is the checksum of the start and of the end of indexed data.
+	Checksum uint32 `json:"checksum"`
+}
+
+// indexEntry points to the first line of candles started at Time.
+type indexEntry struct {
+	Time   time.Time `json:"time"`
+	Offset int64     `json:"offset"`
+}
+
+// offset returns offset lines starting at from or later are located after.
+func (idx *index) offset(from time.Time) int64 {
+	i := sort.Search(len(idx.Entries), func(i int) bool {
+		return idx.Entries[i].Time.After(from)
+	})
+
+	if i == 0 {
+		return 0
+	}
+
+	return idx.Entries[i-1].Offset
+}
+
+// valid reports whether indexed data of file of size is not changed.
+// Modification time is not compared, as it changes on appends and has coarse resolution.
+func (idx *index) valid(f *os.File, size int64) (bool, error) {
+	if idx.Size > size {
+		return false, nil
+	}
+
+	sum, err := checksum(f, idx.Size)
+	if err != nil {
+		return false, err
+	}
+
+	return sum == idx.Checksum, nil
+}
+
+// extend indexes candles appended to file after already indexed data.
+func (idx *index) extend(f *os.File) error {
+	if _, err := f.Seek(idx.Size, io.SeekStart); err != nil {
+		return err
+	}
+
+	r := bufio.NewReader(f)
+
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+
+		// incomplete last line is not indexed, as it can still be written.
+		if err == io.EOF {
+			break
+		}
+
+		offset := idx.Size
+		idx.Size += int64(len(line))
+		idx.Lines++
+
+		t, ok := lineStartTime(line)
+		if !ok || !t.After(idx.Last) {
+			continue
+		}
+
+		if len(idx.Entries) == 0 || idx.Lines >= indexEvery {
+			idx.Entries = append(idx.Entries, indexEntry{Time: t, Offset: offset})
+			idx.Lines = 0
+		}
+
+		idx.Last = t
+	}
+
+	sum, err := checksum(f, idx.Size)
+	if err != nil {
+		return err
+	}
+
+	idx.Checksum = sum
+
+	return nil
+}
+
+// checksum returns checksum of the first and the last checkSize bytes of the first size bytes of file.
+func checksum(f *os.File, size int64) (uint32, error) {
+	head := size
+	if head > checkSize {
+		head = checkSize
+	}
+
+	tail := size - checkSize
+	if tail < head {
+		tail = head
+	}
+
+	buf := make([]byte, head+size-tail)
+
+	if _, err := f.ReadAt(buf[:head], 0); err != nil {
+		return 0, err
+	}
+
+	if _, err := f.ReadAt(buf[head:], tail); err != nil {
+		return 0, err
+	}
+
+	return crc32.ChecksumIEEE(buf), nil
+}
+
+// loadIndex reads index from file.
+func loadIndex(path string) (*index, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	idx := &index{}
+	if err = json.Unmarshal(data, idx); err != nil {
+		return nil, err
+	}
+
+	return idx, nil
+}
+
+// saveIndex atomically replaces index file.
+func saveIndex(path string, idx *index) error {
+	data, err := json.Marshal(idx)
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err = ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,36 @@
+package query
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIndex_Internal_offset(t *testing.T) {
+	start := time.Date(2019, 1, 30, 10, 0, 0, 0, time.UTC)
+	idx := &index{
+		Entries: []indexEntry{
+			{Time: start, Offset: 0},
+			{Time: start.Add(time.Hour), Offset: 100},
+			{Time: start.Add(2 * time.Hour), Offset: 200},
+		},
+	}
+
+	tests := []struct {
+		name string
+		from time.Time
+		want int64
+	}{
+		{name: "before the first entry", from: start.Add(-time.Hour), want: 0},
+		{name: "entry time", from: start.Add(time.Hour), want: 100},
+		{name: "between entries", from: start.Add(90 * time.Minute), want: 100},
+		{name: "after the last entry", from: start.Add(5 * time.Hour), want: 200},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.want, idx.offset(test.from))
+		})
+	}
+}
@@ -0,0 +1,198 @@
+// Package query answers range queries over produced candle files.
+package query
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/candles/pipelines/candles"
+)
+
+// ErrUnknownOutput is returned when there is no candles file of queried output.
+var ErrUnknownOutput = errors.New("no candles of provided output")
+
+// candleFields is the count of Candle.String values at the start of output lines.
+const candleFields = 6
+
+// Query describes candles to look up.
+type Query struct {
+	// Ticker filters candles by ticker, all tickers are returned if it is empty.
+	Ticker string
+	// Output is the name of pipeline output candles are written to, e.g. candle_5min or tick_100.
+	Output string
+	// From and To limit candles start time to [From, To) range.
+	// Zero values mean no limit.
+	From time.Time
+	To   time.Time
+}
+
+// Store answers queries over candle files of a directory.
+// Files are indexed by candle start time on the first query,
+// indexes are saved next to files, extended when files grow
+// and rebuilt when the start or the end of indexed data changes.
+// Store is safe for concurrent use.
+type Store struct {
+	dir string
+
+	mu      sync.Mutex
+	indexes map[string]*index
+}
+
+// NewStore creates new Store of candle files in dir.
+func NewStore(dir string) *Store {
+	return &Store{
+		dir:     dir,
+		indexes: make(map[string]*index),
+	}
+}
+
+// Candles returns candles matching query in file order.
+func (s *Store) Candles(q Query) ([]candles.Candle, error) {
+	// output names are file names in dir, so other paths are never opened.
+	if q.Output == "" || q.Output != filepath.Base(q.Output) || strings.HasPrefix(q.Output, ".") {
+		return nil, ErrUnknownOutput
+	}
+
+	path := filepath.Join(s.dir, q.Output)
+
+	offset, err := s.offset(path, q.From)
+	if os.IsNotExist(err) {
+		return nil, ErrUnknownOutput
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	if _, err = f.Seek(offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	return scan(f, q)
+}
+
+// offset returns offset of candles file candles started at from or later are located after.
+// Index of file is loaded, built or extended if needed.
+func (s *Store) offset(path string, from time.Time) (int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+
+	defer f.Close()
+
+	st, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	idx, ok := s.indexes[path]
+	if !ok {
+		if idx, err = loadIndex(path + indexSuffix); err != nil {
+			idx = &index{}
+		}
+	}
+
+	// files are only appended to while being written,
+	// so index is extended if file grew and rebuilt if indexed data changed.
+	valid, err := idx.valid(f, st.Size())
+	if err != nil {
+		return 0, err
+	}
+
+	if !valid {
+		idx = &index{}
+	}
+
+	if size := idx.Size; size < st.Size() {
+		if err = idx.extend(f); err != nil {
+			return 0, err
+		}
+
+		// index is still used from memory if it can't be saved.
+		if idx.Size != size || !ok || !valid {
+			_ = saveIndex(path+indexSuffix, idx)
+		}
+	}
+
+	s.indexes[path] = idx
+
+	return idx.offset(from), nil
+}
+
+// scan reads candles matching query until the end of its time range.
+func scan(r io.Reader, q Query) ([]candles.Candle, error) {
+	var out []candles.Candle
+
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := sc.Text()
+
+		t, ok := lineStartTime(line)
+		if !ok {
+			continue
+		}
+
+		if !q.To.IsZero() && !t.Before(q.To) {
+			break
+		}
+
+		if t.Before(q.From) {
+			continue
+		}
+
+		if q.Ticker != "" && !strings.HasPrefix(line, q.Ticker+",") {
+			continue
+		}
+
+		c, err := parseLine(line)
+		if err != nil {
+			return nil, err
+		}
+
+		out = append(out, c)
+	}
+
+	return out, sc.Err()
+}
+
+// parseLine parses candle from output line, ignoring additional columns.
+func parseLine(line string) (candles.Candle, error) {
+	values := strings.SplitN(strings.TrimSpace(line), ",", candleFields+1)
+	if len(values) > candleFields {
+		values = values[:candleFields]
+	}
+
+	return candles.CandleFromString(strings.Join(values, ","))
+}
+
+// lineStartTime returns candle start time of output line.
+func lineStartTime(line string) (time.Time, bool) {
+	values := strings.SplitN(line, ",", 3)
+	if len(values) < 3 {
+		return time.Time{}, false
+	}
+
+	t, err := time.Parse(time.RFC3339, values[1])
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return t, true
+}
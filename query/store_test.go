@@ -0,0 +1,168 @@
+package query_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/candles/query"
+)
+
+// writeCandles writes file of 5 minutes candles of two tickers for n intervals.
+func writeCandles(t *testing.T, path string, start time.Time, n int) {
+	lines := make([]string, 0, n*2)
+
+	for i := 0; i < n; i++ {
+		ts := start.Add(time.Duration(i) * 5 * time.Minute).Format(time.RFC3339)
+		lines = append(lines,
+			fmt.Sprintf("AAPL,%s,%d.000000,1.000000,1.000000,1.000000,10,5,5", ts, i),
+			fmt.Sprintf("MSFT,%s,%d.000000,1.000000,1.000000,1.000000,10,5,5", ts, i),
+		)
+	}
+
+	assert.NoError(t, ioutil.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0600))
+}
+
+func TestStore_Candles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "query")
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	start := time.Date(2019, 1, 30, 10, 0, 0, 0, time.UTC)
+	writeCandles(t, filepath.Join(dir, "candle_5min"), start, 1000)
+
+	tests := []struct {
+		name    string
+		q       query.Query
+		want    []string
+		wantErr error
+	}{
+		{
+			name: "ticker and time range",
+			q: query.Query{
+				Ticker: "AAPL",
+				Output: "candle_5min",
+				From:   start.Add(500 * 5 * time.Minute),
+				To:     start.Add(502 * 5 * time.Minute),
+			},
+			want: []string{
+				"AAPL,2019-02-01T03:40:00Z,500.000000,1.000000,1.000000,1.000000",
+				"AAPL,2019-02-01T03:45:00Z,501.000000,1.000000,1.000000,1.000000",
+			},
+		},
+		{
+			name: "all tickers",
+			q: query.Query{
+				Output: "candle_5min",
+				From:   start.Add(999 * 5 * time.Minute),
+			},
+			want: []string{
+				"AAPL,2019-02-02T21:15:00Z,999.000000,1.000000,1.000000,1.000000",
+				"MSFT,2019-02-02T21:15:00Z,999.000000,1.000000,1.000000,1.000000",
+			},
+		},
+		{
+			name: "from the start",
+			q: query.Query{
+				Ticker: "MSFT",
+				Output: "candle_5min",
+				To:     start.Add(5 * time.Minute),
+			},
+			want: []string{
+				"MSFT,2019-01-30T10:00:00Z,0.000000,1.000000,1.000000,1.000000",
+			},
+		},
+		{
+			name:    "unknown output",
+			q:       query.Query{Output: "candle_30min"},
+			wantErr: query.ErrUnknownOutput,
+		},
+		{
+			name:    "output outside of dir",
+			q:       query.Query{Output: "../candle_5min"},
+			wantErr: query.ErrUnknownOutput,
+		},
+	}
+
+	s := query.NewStore(dir)
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cs, err := s.Candles(test.q)
+			assert.Equal(t, test.wantErr, err)
+
+			got := make([]string, 0, len(cs))
+			for i := range cs {
+				got = append(got, cs[i].String())
+			}
+
+			if test.want != nil {
+				assert.Equal(t, test.want, got)
+			}
+		})
+	}
+
+	_, err = os.Stat(filepath.Join(dir, "candle_5min.idx"))
+	assert.NoError(t, err)
+}
+
+func TestStore_Candles_changedFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "query")
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "candle_5min")
+	start := time.Date(2019, 1, 30, 10, 0, 0, 0, time.UTC)
+	writeCandles(t, path, start, 10)
+
+	q := query.Query{Ticker: "MSFT", Output: "candle_5min", From: start.Add(9 * 5 * time.Minute)}
+
+	cs, err := query.NewStore(dir).Candles(q)
+	assert.NoError(t, err)
+	assert.Len(t, cs, 1)
+
+	writeCandles(t, path, start, 600)
+
+	cs, err = query.NewStore(dir).Candles(q)
+	assert.NoError(t, err)
+	assert.Len(t, cs, 591)
+}
+
+func TestStore_Candles_rewrittenFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "query")
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "candle_5min")
+	start := time.Date(2019, 1, 30, 10, 0, 0, 0, time.UTC)
+	writeCandles(t, path, start, 600)
+
+	s := query.NewStore(dir)
+
+	cs, err := s.Candles(query.Query{Ticker: "MSFT", Output: "candle_5min", From: start})
+	assert.NoError(t, err)
+	assert.Len(t, cs, 600)
+
+	// content of equal length with later candles.
+	start = start.AddDate(0, 0, 10)
+	writeCandles(t, path, start, 600)
+
+	q := query.Query{Ticker: "MSFT", Output: "candle_5min", From: start}
+
+	cs, err = s.Candles(q)
+	assert.NoError(t, err)
+	assert.Len(t, cs, 600)
+
+	cs, err = query.NewStore(dir).Candles(q)
+	assert.NoError(t, err)
+	assert.Len(t, cs, 600)
+}
@@ -0,0 +1,125 @@
+package server
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/candles/pipelines/candles"
+)
+
+// subscriberBuffer is the count of events buffered for each subscriber.
+const subscriberBuffer = 1024
+
+// event is a candle of pipeline output encoded to JSON.
+type event struct {
+	name string
+	data []byte
+}
+
+// subscription receives events of pipeline matching its filters.
+type subscription struct {
+	// name filters events by output name, all outputs match if it is empty.
+	name string
+	// ticker filters events by candle ticker, all tickers match if it is empty.
+	ticker string
+	events chan event
+}
+
+// Hub delivers candles of pipelines outputs to stream subscribers.
+// Subscribers which don't keep up with candles are dropped.
+type Hub struct {
+	mu     sync.Mutex
+	subs   map[*subscription]struct{}
+	closed bool
+}
+
+// NewHub creates new Hub.
+func NewHub() *Hub {
+	return &Hub{
+		subs: make(map[*subscription]struct{}),
+	}
+}
+
+// Publish sends candles of pipeline output with provided name to subscribers.
+// Never blocks, so it can be used as pipeline candles handler.
+func (h *Hub) Publish(name string, cs []candles.Candle) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(h.subs) == 0 {
+		return
+	}
+
+	for i := range cs {
+		data, err := json.Marshal(&cs[i])
+		if err != nil {
+			continue
+		}
+
+		e := event{name: name, data: data}
+
+		for s := range h.subs {
+			if s.name != "" && s.name != name || s.ticker != "" && s.ticker != cs[i].Ticker() {
+				continue
+			}
+
+			select {
+			case s.events <- e:
+			default:
+				h.drop(s)
+			}
+		}
+	}
+}
+
+// subscribe creates new subscription with provided filters.
+func (h *Hub) subscribe(name, ticker string) *subscription {
+	s := &subscription{
+		name:   name,
+		ticker: ticker,
+		events: make(chan event, subscriberBuffer),
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		close(s.events)
+		return s
+	}
+
+	h.subs[s] = struct{}{}
+
+	return s
+}
+
+// Close drops all subscriptions, so streams are finished.
+// Subscriptions created after Close are finished right away.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+
+	for s := range h.subs {
+		h.drop(s)
+	}
+}
+
+// unsubscribe removes subscription from hub.
+func (h *Hub) unsubscribe(s *subscription) {
+	h.mu.Lock()
+	h.drop(s)
+	h.mu.Unlock()
+}
+
+// drop removes subscription and closes its events chan.
+// Has to be called with mu locked.
+func (h *Hub) drop(s *subscription) {
+	if _, ok := h.subs[s]; !ok {
+		return
+	}
+
+	delete(h.subs, s)
+	close(s.events)
+}
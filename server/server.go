@@ -0,0 +1,260 @@
+// Package server serves produced candles over HTTP.
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/candles/pipelines"
+	"github.com/candles/pipelines/candles"
+	"github.com/candles/query"
+)
+
+var (
+	errStreamUnsupported = errors.New("streaming is not supported")
+	errInvalidInterval   = errors.New("invalid interval")
+	errNoOutput          = errors.New("no output or interval")
+	errInvalidTime       = errors.New("invalid time")
+)
+
+// Controller adds and removes interval pipelines while candles are served.
+type Controller interface {
+	AddInterval(interval int) error
+	RemoveInterval(interval int) error
+}
+
+// Server serves candles history from candle files
+// and streams candles published to hub.
+type Server struct {
+	store *query.Store
+	hub   *Hub
+	ctl   Controller
+	// outputs returns names of served outputs, all outputs are served if it is nil.
+	outputs func() []string
+
+	l *logrus.Logger
+}
+
+// New creates new Server.
+func New(store *query.Store, hub *Hub, l *logrus.Logger) *Server {
+	return &Server{
+		store: store,
+		hub:   hub,
+		l:     l,
+	}
+}
+
+// SetController makes server add and remove interval pipelines using c.
+func (s *Server) SetController(c Controller) {
+	s.ctl = c
+}
+
+// SetOutputs makes server serve only outputs with names returned by outputs,
+// e.g. by Pipelines.Outputs, so outputs added and removed at runtime are resolved.
+func (s *Server) SetOutputs(outputs func() []string) {
+	s.outputs = outputs
+}
+
+// Handler returns HTTP handler of server API.
+// GET /candles?ticker=&output=&from=&to= returns JSON array of candles,
+// GET /stream?ticker=&output= streams candles as server-sent events named by their outputs.
+// If controller is set, POST /pipelines?interval= adds interval pipeline
+// and DELETE /pipelines?interval= removes it.
+// Output is the name of pipeline output, e.g. candle_5min, tick_100 or ha_candle_5min,
+// interval in minutes can be provided instead for time candles output.
+// From and to are RFC3339 times.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/candles", s.handleCandles)
+	mux.HandleFunc("/stream", s.handleStream)
+
+	if s.ctl != nil {
+		mux.HandleFunc("/pipelines", s.handlePipelines)
+	}
+
+	return mux
+}
+
+// handleCandles responds with candles matching query.
+func (s *Server) handleCandles(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	q, err := parseQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !s.served(q.Output) {
+		http.Error(w, query.ErrUnknownOutput.Error(), http.StatusNotFound)
+		return
+	}
+
+	cs, err := s.store.Candles(q)
+	if errors.Is(err, query.ErrUnknownOutput) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	if err != nil {
+		s.l.Errorf("can't query candles: %v", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+
+		return
+	}
+
+	if cs == nil {
+		cs = []candles.Candle{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err = json.NewEncoder(w).Encode(cs); err != nil {
+		s.l.Errorf("can't write candles: %v", err)
+	}
+}
+
+// handleStream streams candles until client disconnects
+// or stops keeping up with them.
+func (s *Server) handleStream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, errStreamUnsupported.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	name, err := parseOutput(r.URL.Query())
+	if err != nil && !errors.Is(err, errNoOutput) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if name != "" && !s.served(name) {
+		http.Error(w, query.ErrUnknownOutput.Error(), http.StatusNotFound)
+		return
+	}
+
+	sub := s.hub.subscribe(name, r.URL.Query().Get("ticker"))
+	defer s.hub.unsubscribe(sub)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e, ok := <-sub.events:
+			if !ok {
+				return
+			}
+
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.name, e.data); err != nil {
+				return
+			}
+
+			flusher.Flush()
+		}
+	}
+}
+
+// handlePipelines adds or removes interval pipeline.
+// Pipelines which can't be added or removed are reported as conflicts.
+func (s *Server) handlePipelines(w http.ResponseWriter, r *http.Request) {
+	interval, err := strconv.Atoi(r.URL.Query().Get("interval"))
+	if err != nil || interval <= 0 {
+		http.Error(w, errInvalidInterval.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var code int
+
+	switch r.Method {
+	case http.MethodPost:
+		err, code = s.ctl.AddInterval(interval), http.StatusCreated
+	case http.MethodDelete:
+		err, code = s.ctl.RemoveInterval(interval), http.StatusNoContent
+	default:
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	w.WriteHeader(code)
+}
+
+// served reports whether output with provided name is served.
+func (s *Server) served(name string) bool {
+	if s.outputs == nil {
+		return true
+	}
+
+	for _, o := range s.outputs() {
+		if o == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseOutput returns output name from request parameters,
+// interval is resolved to the name of time candles output.
+func parseOutput(values url.Values) (string, error) {
+	if v := values.Get("output"); v != "" {
+		return v, nil
+	}
+
+	v := values.Get("interval")
+	if v == "" {
+		return "", errNoOutput
+	}
+
+	interval, err := strconv.Atoi(v)
+	if err != nil {
+		return "", errInvalidInterval
+	}
+
+	return pipelines.IntervalOutput(interval), nil
+}
+
+// parseQuery parses candles query from request parameters.
+func parseQuery(r *http.Request) (query.Query, error) {
+	values := r.URL.Query()
+	q := query.Query{Ticker: values.Get("ticker")}
+
+	var err error
+
+	if q.Output, err = parseOutput(values); err != nil {
+		return query.Query{}, err
+	}
+
+	for name, dst := range map[string]*time.Time{"from": &q.From, "to": &q.To} {
+		v := values.Get(name)
+		if v == "" {
+			continue
+		}
+
+		if *dst, err = time.Parse(time.RFC3339, v); err != nil {
+			return query.Query{}, errInvalidTime
+		}
+	}
+
+	return q, nil
+}
@@ -0,0 +1,238 @@
+package server_test
+
+import (
+	"bufio"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/candles/pipelines/candles"
+	"github.com/candles/query"
+	"github.com/candles/server"
+)
+
+func TestServer_candles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "server")
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "candle_5min"), []byte(
+		"AAPL,2019-01-30T10:00:00Z,1.000000,2.000000,0.500000,1.500000\n"+
+			"MSFT,2019-01-30T10:00:00Z,1.000000,2.000000,0.500000,1.500000\n"+
+			"AAPL,2019-01-30T10:05:00Z,1.500000,2.000000,0.500000,1.000000\n",
+	), 0600))
+
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "tick_100"), []byte(
+		"AAPL,2019-01-30T10:00:00Z,1.000000,2.000000,0.500000,1.500000\n",
+	), 0600))
+
+	s := server.New(query.NewStore(dir), server.NewHub(), logrus.New())
+	s.SetOutputs(func() []string { return []string{"candle_5min", "tick_100", "candle_30min"} })
+
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+
+	tests := []struct {
+		name     string
+		url      string
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "success",
+			url:      "/candles?ticker=AAPL&interval=5&from=2019-01-30T10:05:00Z",
+			wantCode: http.StatusOK,
+			wantBody: `[{"ticker":"AAPL","start_time":"2019-01-30T10:05:00Z","open":1.5,"high":2,"low":0.5,"close":1}]` + "\n",
+		},
+		{
+			name:     "no candles",
+			url:      "/candles?ticker=TSLA&interval=5",
+			wantCode: http.StatusOK,
+			wantBody: "[]\n",
+		},
+		{
+			name:     "output",
+			url:      "/candles?output=tick_100",
+			wantCode: http.StatusOK,
+			wantBody: `[{"ticker":"AAPL","start_time":"2019-01-30T10:00:00Z","open":1,"high":2,"low":0.5,"close":1.5}]` + "\n",
+		},
+		{
+			name:     "unknown interval",
+			url:      "/candles?interval=30",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "not served output",
+			url:      "/candles?output=candle_5min.idx",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "no output",
+			url:      "/candles?ticker=AAPL",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "invalid interval",
+			url:      "/candles?interval=five",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "invalid time",
+			url:      "/candles?interval=5&to=yesterday",
+			wantCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resp, err := http.Get(srv.URL + test.url)
+			assert.NoError(t, err)
+
+			defer resp.Body.Close()
+
+			body, err := ioutil.ReadAll(resp.Body)
+			assert.NoError(t, err)
+			assert.Equal(t, test.wantCode, resp.StatusCode)
+
+			if test.wantBody != "" {
+				assert.Equal(t, test.wantBody, string(body))
+			}
+		})
+	}
+}
+
+func TestServer_stream(t *testing.T) {
+	hub := server.NewHub()
+
+	s := server.New(query.NewStore(""), hub, logrus.New())
+	s.SetOutputs(func() []string { return []string{"candle_5min", "ha_candle_5min"} })
+
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/stream?output=candle_30min")
+	assert.NoError(t, err)
+
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp, err = http.Get(srv.URL + "/stream?output=ha_candle_5min&ticker=AAPL")
+	assert.NoError(t, err)
+
+	defer resp.Body.Close()
+
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	iStart := time.Date(2019, 1, 30, 10, 0, 0, 0, time.UTC)
+	cs := []candles.Candle{
+		*candles.New(candles.MustTradeFromString("AAPL,1,10,2019-01-30 10:00:01"), iStart),
+		*candles.New(candles.MustTradeFromString("MSFT,2,10,2019-01-30 10:00:01"), iStart),
+	}
+
+	hub.Publish("candle_5min", cs)
+	hub.Publish("ha_candle_5min", cs)
+	hub.Close()
+
+	body, err := ioutil.ReadAll(bufio.NewReader(resp.Body))
+	assert.NoError(t, err)
+
+	events := strings.Split(strings.TrimSpace(string(body)), "\n\n")
+	assert.Len(t, events, 1)
+	assert.True(t, strings.HasPrefix(events[0], "event: ha_candle_5min\ndata: {\"ticker\":\"AAPL\""))
+}
+
+var errControllerMock = errors.New("pipeline exists")
+
+// controllerMock records added and removed intervals, failing to add existing ones.
+type controllerMock struct {
+	intervals map[int]bool
+}
+
+func (c controllerMock) AddInterval(interval int) error {
+	if c.intervals[interval] {
+		return errControllerMock
+	}
+
+	c.intervals[interval] = true
+
+	return nil
+}
+
+func (c controllerMock) RemoveInterval(interval int) error {
+	delete(c.intervals, interval)
+	return nil
+}
+
+func TestServer_pipelines(t *testing.T) {
+	ctl := controllerMock{intervals: map[int]bool{5: true}}
+
+	s := server.New(query.NewStore(""), server.NewHub(), logrus.New())
+	s.SetController(ctl)
+
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+
+	tests := []struct {
+		name     string
+		method   string
+		url      string
+		wantCode int
+	}{
+		{
+			name:     "add",
+			method:   http.MethodPost,
+			url:      "/pipelines?interval=15",
+			wantCode: http.StatusCreated,
+		},
+		{
+			name:     "add existing",
+			method:   http.MethodPost,
+			url:      "/pipelines?interval=5",
+			wantCode: http.StatusConflict,
+		},
+		{
+			name:     "remove",
+			method:   http.MethodDelete,
+			url:      "/pipelines?interval=5",
+			wantCode: http.StatusNoContent,
+		},
+		{
+			name:     "invalid interval",
+			method:   http.MethodPost,
+			url:      "/pipelines?interval=0",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "invalid method",
+			method:   http.MethodGet,
+			url:      "/pipelines?interval=5",
+			wantCode: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req, err := http.NewRequest(test.method, srv.URL+test.url, nil)
+			assert.NoError(t, err)
+
+			resp, err := http.DefaultClient.Do(req)
+			assert.NoError(t, err)
+
+			resp.Body.Close()
+
+			assert.Equal(t, test.wantCode, resp.StatusCode)
+		})
+	}
+
+	assert.Equal(t, map[int]bool{15: true}, ctl.intervals)
+}
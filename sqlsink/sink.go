@@ -0,0 +1,206 @@
+// Package sqlsink writes candles to SQL databases.
+// Schema and statements are compatible with SQLite and PostgreSQL/TimescaleDB,
+// drivers are registered by the importing program.
+package sqlsink
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/candles/pipelines"
+)
+
+const (
+	// rowArgs is the count of inserted values of a single candle.
+	rowArgs = 9
+	// maxRows limits candles per statement to keep its arguments count
+	// below the SQLite limit of 999.
+	maxRows = 100
+)
+
+// Builder creates sinks upserting candles of pipelines to the table.
+// Candles are keyed by ticker, interval and start time,
+// so reruns and repeated partial candles update rows instead of duplicating them.
+// Interval is the pipeline output name, e.g. candle_5min.
+// Table name is quoted, so it is case sensitive and may be qualified by schema, e.g. public.candles.
+type Builder struct {
+	db    *sql.DB
+	table string
+}
+
+// NewBuilder creates new Builder writing to the table of db.
+func NewBuilder(db *sql.DB, table string) Builder {
+	return Builder{
+		db:    db,
+		table: quoteIdent(table),
+	}
+}
+
+// quoteIdent quotes each dot separated part of SQL identifier,
+// so it can't be interpreted as SQL.
+func quoteIdent(name string) string {
+	parts := strings.Split(name, ".")
+	for i, p := range parts {
+		parts[i] = `"` + strings.ReplaceAll(p, `"`, `""`) + `"`
+	}
+
+	return strings.Join(parts, ".")
+}
+
+// CreateTable creates candles table if it doesn't exist.
+// Existing table isn't altered, so table created without volume column has to be migrated.
+func (b Builder) CreateTable(ctx context.Context) error {
+	_, err := b.db.ExecContext(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	ticker TEXT NOT NULL,
+	"interval" TEXT NOT NULL,
+	start_time TIMESTAMP NOT NULL,
+	open DOUBLE PRECISION NOT NULL,
+	high DOUBLE PRECISION NOT NULL,
+	low DOUBLE PRECISION NOT NULL,
+	close DOUBLE PRECISION NOT NULL,
+	volume BIGINT NOT NULL DEFAULT 0,
+	extra TEXT NOT NULL DEFAULT '',
+	PRIMARY KEY (ticker, "interval", start_time)
+)`, b.table))
+
+	return err
+}
+
+// New creates new Sink of pipeline output.
+func (b Builder) New(name string) (pipelines.Sink, error) {
+	return &Sink{
+		db:       b.db,
+		table:    b.table,
+		interval: name,
+	}, nil
+}
+
+// Open creates new Sink of resumed pipeline output.
+// Rows written after the checkpoint are upserted again, so size is ignored.
+func (b Builder) Open(name string, _ int64) (pipelines.Sink, error) {
+	return b.New(name)
+}
+
+// Sink upserts candles lines of pipeline output.
+// Each write is committed in a single transaction.
+type Sink struct {
+	db       *sql.DB
+	table    string
+	interval string
+
+	mu      sync.Mutex
+	written int64
+}
+
+// Write upserts candles of batch.
+// Additional columns of candles are joined into extra column.
+func (s *Sink) Write(b pipelines.Batch) error {
+	if len(b.Candles) == 0 {
+		return nil
+	}
+
+	rows := make([][]interface{}, 0, len(b.Candles))
+
+	for i := range b.Candles {
+		c := &b.Candles[i]
+
+		extra := ""
+		if i < len(b.Columns) {
+			extra = strings.Join(b.Columns[i], ",")
+		}
+
+		rows = append(rows, []interface{}{
+			c.Ticker(), s.interval, c.StartTime(),
+			c.OpenPrice(), c.HighPrice(), c.LowPrice(), c.ClosePrice(),
+			int64(c.Volume()), extra,
+		})
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.upsert(rows); err != nil {
+		return err
+	}
+
+	s.written += int64(len(rows))
+
+	return nil
+}
+
+// Sync returns count of upserted candles. Writes are already committed.
+func (s *Sink) Sync() (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.written, nil
+}
+
+// Close does nothing, as database is shared by sinks.
+func (s *Sink) Close() {}
+
+// upsert upserts rows in a single transaction.
+func (s *Sink) upsert(rows [][]interface{}) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	for len(rows) > 0 {
+		n := len(rows)
+		if n > maxRows {
+			n = maxRows
+		}
+
+		query, args := upsertQuery(s.table, rows[:n])
+		if _, err = tx.Exec(query, args...); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+
+		rows = rows[n:]
+	}
+
+	return tx.Commit()
+}
+
+// upsertQuery returns statement upserting rows to the table and its arguments.
+func upsertQuery(table string, rows [][]interface{}) (string, []interface{}) {
+	var sb strings.Builder
+
+	args := make([]interface{}, 0, len(rows)*rowArgs)
+
+	sb.WriteString("INSERT INTO ")
+	sb.WriteString(table)
+	sb.WriteString(` (ticker, "interval", start_time, open, high, low, close, volume, extra) VALUES `)
+
+	for i, row := range rows {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+
+		sb.WriteString("(")
+
+		for j := range row {
+			if j > 0 {
+				sb.WriteString(", ")
+			}
+
+			sb.WriteString("$" + strconv.Itoa(len(args)+j+1))
+		}
+
+		sb.WriteString(")")
+
+		args = append(args, row...)
+	}
+
+	sb.WriteString(` ON CONFLICT (ticker, "interval", start_time) DO UPDATE SET` +
+		` open = excluded.open, high = excluded.high, low = excluded.low,` +
+		` close = excluded.close, volume = excluded.volume, extra = excluded.extra`)
+
+	return sb.String(), args
+}
@@ -0,0 +1,143 @@
+package sqlsink_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/candles/pipelines"
+	"github.com/candles/pipelines/candles"
+	"github.com/candles/sqlsink"
+)
+
+// recorder is a database driver recording executed statements.
+type recorder struct {
+	mu    sync.Mutex
+	execs []string
+	args  [][]driver.Value
+}
+
+func (r *recorder) Open(string) (driver.Conn, error) { return conn{r}, nil }
+
+func (r *recorder) record(query string, args []driver.Value) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.execs = append(r.execs, query)
+	r.args = append(r.args, args)
+}
+
+type conn struct{ r *recorder }
+
+func (c conn) Prepare(query string) (driver.Stmt, error) { return stmt{c.r, query}, nil }
+func (c conn) Close() error                              { return nil }
+func (c conn) Begin() (driver.Tx, error) {
+	c.r.record("BEGIN", nil)
+	return tx{c.r}, nil
+}
+
+type tx struct{ r *recorder }
+
+func (t tx) Commit() error {
+	t.r.record("COMMIT", nil)
+	return nil
+}
+
+func (t tx) Rollback() error {
+	t.r.record("ROLLBACK", nil)
+	return nil
+}
+
+type stmt struct {
+	r     *recorder
+	query string
+}
+
+func (s stmt) Close() error  { return nil }
+func (s stmt) NumInput() int { return -1 }
+func (s stmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.r.record(s.query, args)
+	return driver.RowsAffected(1), nil
+}
+
+func (s stmt) Query([]driver.Value) (driver.Rows, error) { return nil, driver.ErrSkip }
+
+var rec = &recorder{}
+
+// mustCandle parses candle from line.
+func mustCandle(t *testing.T, line string) candles.Candle {
+	c, err := candles.CandleFromString(line)
+	assert.NoError(t, err)
+
+	return c
+}
+
+func init() {
+	sql.Register("recorder", rec)
+}
+
+func TestSink(t *testing.T) {
+	db, err := sql.Open("recorder", "")
+	assert.NoError(t, err)
+
+	defer db.Close()
+
+	b := sqlsink.NewBuilder(db, "candles")
+	assert.NoError(t, b.CreateTable(context.Background()))
+
+	w, err := b.New("candle_5min")
+	assert.NoError(t, err)
+
+	assert.NoError(t, w.Write(pipelines.Batch{
+		Output:   "candle_5min",
+		Interval: 5,
+		Candles: []candles.Candle{
+			*candles.New(candles.MustTradeFromString("AAPL,1.5,10,2019-01-30 10:01:00"), time.Date(2019, 1, 30, 10, 0, 0, 0, time.UTC)),
+			mustCandle(t, "SBER,2019-01-30T10:00:00Z,3.000000,4.000000,2.000000,3.000000"),
+		},
+		Columns: [][]string{nil, {"10,5,5", "final"}},
+	}))
+	assert.NoError(t, w.Write(pipelines.Batch{}))
+
+	size, err := w.(*sqlsink.Sink).Sync()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), size)
+
+	w.Close()
+
+	assert.Len(t, rec.execs, 4)
+	assert.True(t, strings.HasPrefix(rec.execs[0], `CREATE TABLE IF NOT EXISTS "candles" (`))
+	assert.Equal(t, "BEGIN", rec.execs[1])
+	assert.Equal(t, `INSERT INTO "candles" (ticker, "interval", start_time, open, high, low, close, volume, extra) `+
+		`VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9), ($10, $11, $12, $13, $14, $15, $16, $17, $18) `+
+		`ON CONFLICT (ticker, "interval", start_time) DO UPDATE SET `+
+		`open = excluded.open, high = excluded.high, low = excluded.low, `+
+		`close = excluded.close, volume = excluded.volume, extra = excluded.extra`, rec.execs[2])
+	assert.Equal(t, []driver.Value{
+		"AAPL", "candle_5min", time.Date(2019, 1, 30, 10, 0, 0, 0, time.UTC), 1.5, 1.5, 1.5, 1.5, int64(10), "",
+		"SBER", "candle_5min", time.Date(2019, 1, 30, 10, 0, 0, 0, time.UTC), 3.0, 4.0, 2.0, 3.0, int64(0), "10,5,5,final",
+	}, rec.args[2])
+	assert.Equal(t, "COMMIT", rec.execs[3])
+}
+
+func TestNewBuilder_quotedTable(t *testing.T) {
+	db, err := sql.Open("recorder", "")
+	assert.NoError(t, err)
+
+	defer db.Close()
+
+	rec.mu.Lock()
+	rec.execs, rec.args = nil, nil
+	rec.mu.Unlock()
+
+	assert.NoError(t, sqlsink.NewBuilder(db, `public.candles"; DROP TABLE candles; --`).CreateTable(context.Background()))
+
+	assert.Len(t, rec.execs, 1)
+	assert.True(t, strings.HasPrefix(rec.execs[0], `CREATE TABLE IF NOT EXISTS "public"."candles""; DROP TABLE candles; --" (`))
+}
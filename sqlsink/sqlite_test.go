@@ -0,0 +1,170 @@
+package sqlsink_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/candles/pipelines"
+	"github.com/candles/pipelines/candles"
+	"github.com/candles/sqlsink"
+)
+
+// sqliteShell is a database driver executing statements by sqlite3 command line shell,
+// as module has no SQLite driver dependency.
+// Arguments are inlined as SQL literals and statements of transaction are executed by a single call.
+type sqliteShell struct{}
+
+func (sqliteShell) Open(path string) (driver.Conn, error) { return &shellConn{path: path}, nil }
+
+type shellConn struct {
+	path string
+	// tx contains statements of not committed transaction, it is nil outside of transaction.
+	tx []string
+}
+
+func (c *shellConn) Prepare(query string) (driver.Stmt, error) { return shellStmt{c, query}, nil }
+func (c *shellConn) Close() error                              { return nil }
+func (c *shellConn) Begin() (driver.Tx, error) {
+	c.tx = []string{"BEGIN"}
+	return c, nil
+}
+
+func (c *shellConn) Commit() error {
+	script := strings.Join(append(c.tx, "COMMIT"), ";\n")
+	c.tx = nil
+
+	return c.run(script)
+}
+
+func (c *shellConn) Rollback() error {
+	c.tx = nil
+	return nil
+}
+
+// run executes script, stopping at the first error.
+func (c *shellConn) run(script string) error {
+	out, err := exec.Command("sqlite3", "-bail", c.path, script+";").CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%v: %s", err, out)
+	}
+
+	return nil
+}
+
+type shellStmt struct {
+	c     *shellConn
+	query string
+}
+
+var placeholder = regexp.MustCompile(`\$\d+`)
+
+func (s shellStmt) Close() error  { return nil }
+func (s shellStmt) NumInput() int { return -1 }
+func (s shellStmt) Exec(args []driver.Value) (driver.Result, error) {
+	var err error
+
+	query := placeholder.ReplaceAllStringFunc(s.query, func(p string) string {
+		i, _ := strconv.Atoi(p[1:])
+		if i > len(args) {
+			err = fmt.Errorf("no argument %s", p)
+			return p
+		}
+
+		return literal(args[i-1])
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if s.c.tx != nil {
+		s.c.tx = append(s.c.tx, query)
+		return driver.RowsAffected(1), nil
+	}
+
+	return driver.RowsAffected(1), s.c.run(query)
+}
+
+func (s shellStmt) Query([]driver.Value) (driver.Rows, error) { return nil, driver.ErrSkip }
+
+// literal returns SQL literal of value, times are formatted as Go SQLite drivers store them.
+func literal(v driver.Value) string {
+	switch v := v.(type) {
+	case nil:
+		return "NULL"
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case time.Time:
+		return literal(v.Format("2006-01-02 15:04:05.999999999-07:00"))
+	default:
+		return "'" + strings.ReplaceAll(fmt.Sprint(v), "'", "''") + "'"
+	}
+}
+
+func init() {
+	sql.Register("sqlite3shell", sqliteShell{})
+}
+
+func TestSink_sqlite(t *testing.T) {
+	if _, err := exec.LookPath("sqlite3"); err != nil {
+		t.Skip("sqlite3 shell is not installed")
+	}
+
+	dir, err := ioutil.TempDir("", "sqlsink")
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "candles.db")
+
+	db, err := sql.Open("sqlite3shell", path)
+	assert.NoError(t, err)
+
+	defer db.Close()
+
+	b := sqlsink.NewBuilder(db, `main.my "candles"`)
+	assert.NoError(t, b.CreateTable(context.Background()))
+	assert.NoError(t, b.CreateTable(context.Background()))
+
+	w, err := b.New("candle_5min")
+	assert.NoError(t, err)
+
+	start := time.Date(2019, 1, 30, 10, 0, 0, 0, time.UTC)
+
+	c := candles.New(candles.MustTradeFromString("AAPL,1.5,10,2019-01-30 10:01:00"), start)
+
+	assert.NoError(t, w.Write(pipelines.Batch{
+		Candles: []candles.Candle{*c, mustCandle(t, "SBER,2019-01-30T10:00:00Z,3.000000,4.000000,2.000000,3.000000")},
+		Columns: [][]string{{"10,0,10"}, {"10,5,5"}},
+	}))
+
+	// candle written again is updated.
+	c.AddTrade(candles.MustTradeFromString("AAPL,2,5,2019-01-30 10:02:00"))
+
+	assert.NoError(t, w.Write(pipelines.Batch{
+		Candles: []candles.Candle{*c},
+		Columns: [][]string{{"15,0,15"}},
+	}))
+
+	out, err := exec.Command("sqlite3", "-separator", "|", path,
+		`SELECT ticker, "interval", start_time, open, high, low, close, volume, extra `+
+			`FROM "main"."my ""candles""" ORDER BY ticker`).CombinedOutput()
+	assert.NoError(t, err, string(out))
+
+	assert.Equal(t, "AAPL|candle_5min|2019-01-30 10:00:00+00:00|1.5|2.0|1.5|2.0|15|15,0,15\n"+
+		"SBER|candle_5min|2019-01-30 10:00:00+00:00|3.0|4.0|2.0|3.0|0|10,5,5\n", string(out))
+}